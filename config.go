@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Broderick-Westrope/amalgo/internal"
+	"github.com/alecthomas/kong"
+)
+
+// configIgnoredFlags are the flags which cannot be set by a config file.
+var configIgnoredFlags = map[string]bool{"help": true, "version": true, "config": true, "profile": true, "dir": true}
+
+// configPathFlags are the flags whose relative paths are resolved against the directory of the config file.
+var configPathFlags = map[string]bool{"output": true, "gitignore": true}
+
+// configResolver supplies the values of flags from a config file. Flags set on the command-line or through
+// their environment variable take precedence over the selected profile, which takes precedence over the
+// top-level settings of the config file.
+type configResolver struct {
+	config   *internal.Config // Nil when there is no config file
+	profile  string
+	settings map[string]any
+	sources  map[string]string // Profile each setting was taken from, which is empty for top-level settings
+	err      error             // Error loading the config file, which is not a usage error
+}
+
+// BeforeResolve loads the config file into the resolver before the flags are resolved. The file is given by
+// '--config', or discovered from the analyzed directory upward. Errors are kept by the resolver, so that they
+// are reported after parsing rather than along with the usage.
+func (c *RootCmd) BeforeResolve(ctx *kong.Context, resolver *configResolver) error {
+	resolver.err = loadConfig(ctx, resolver)
+	return nil
+}
+
+func loadConfig(ctx *kong.Context, resolver *configResolver) error {
+	flags := make(map[string]*kong.Flag)
+	for _, flag := range ctx.Flags() {
+		flags[flag.Name] = flag
+	}
+
+	path, _ := ctx.FlagValue(flags["config"]).(string)
+	if path == "" {
+		var err error
+		path, err = internal.FindConfig(targetDir(ctx))
+		if err != nil {
+			return fmt.Errorf("finding config file: %w", err)
+		}
+	}
+	profile, _ := ctx.FlagValue(flags["profile"]).(string)
+	if path == "" {
+		if profile != "" {
+			return fmt.Errorf("profile %q was selected but no config file was found", profile)
+		}
+		return nil
+	}
+
+	config, err := internal.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	settings, sources, err := config.Resolve(profile)
+	if err != nil {
+		return err
+	}
+	for name, value := range settings {
+		if _, ok := flags[name]; !ok || configIgnoredFlags[name] {
+			return fmt.Errorf("unknown setting %q in config file %q", name, path)
+		}
+		if configPathFlags[name] {
+			settings[name] = resolveConfigPaths(filepath.Dir(path), value)
+		}
+	}
+
+	resolver.config = config
+	resolver.profile = profile
+	resolver.settings = settings
+	resolver.sources = sources
+	return nil
+}
+
+func (r *configResolver) Validate(_ *kong.Application) error { return nil }
+
+func (r *configResolver) Resolve(_ *kong.Context, _ *kong.Path, flag *kong.Flag) (any, error) {
+	value, ok := r.settings[flag.Name]
+	if !ok || envSet(flag) != "" {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// source describes where the value of a flag was taken from.
+func (r *configResolver) source(ctx *kong.Context, flag *kong.Flag) string {
+	for _, path := range ctx.Path {
+		if path.Flag == flag && !path.Resolved {
+			return "flag"
+		}
+	}
+	if env := envSet(flag); env != "" {
+		return "env " + env
+	}
+	if profile, ok := r.sources[flag.Name]; ok {
+		if profile != "" {
+			return fmt.Sprintf("profile %q", profile)
+		}
+		return "config"
+	}
+	return "default"
+}
+
+// envSet returns the environment variable setting the flag, or an empty string if there is none.
+func envSet(flag *kong.Flag) string {
+	for _, env := range flag.Tag.Envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return env
+		}
+	}
+	return ""
+}
+
+// targetDir returns the directory given to the selected command, or the first of its paths, which defaults to
+// the working directory.
+func targetDir(ctx *kong.Context) string {
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "dir" {
+			if dir, _ := ctx.FlagValue(flag).(string); dir != "" {
+				return dir
+			}
+		}
+	}
+	for _, path := range ctx.Path {
+		if path.Positional == nil {
+			continue
+		}
+		switch value := ctx.Value(path).Interface().(type) {
+		case string:
+			if path.Positional.Name == "dir" && value != "" {
+				return value
+			}
+		case []string:
+			if path.Positional.Name == "path" && len(value) > 0 {
+				return value[0]
+			}
+		}
+	}
+	return "."
+}
+
+// resolveConfigPaths resolves the relative paths of a setting against the given directory.
+func resolveConfigPaths(dir string, value any) any {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	if values, ok := value.([]any); ok {
+		resolved := make([]any, 0, len(values))
+		for _, v := range values {
+			resolved = append(resolved, resolve(v.(string)))
+		}
+		return resolved
+	}
+	return resolve(value.(string))
+}
+
+type ConfigCmd struct {
+	Show ConfigShowCmd `cmd:"" help:"Shows the effective value of each flag and where it was taken from: a flag, an environment variable, a profile, the config file, or the default."`
+}
+
+type ConfigShowCmd struct {
+	Dir string `arg:"" optional:"" help:"Directory to analyze, from which the config file is discovered." type:"path" default:"."`
+}
+
+func (s *ConfigShowCmd) Run(ctx *kong.Context, resolver *configResolver) error {
+	if resolver.config == nil {
+		fmt.Println("Config file: none")
+	} else {
+		fmt.Printf("Config file: %s\n", resolver.config.Path)
+	}
+	if resolver.profile != "" {
+		fmt.Printf("Profile: %s\n", resolver.profile)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tVALUE\tSOURCE")
+	for _, flag := range ctx.Flags() {
+		if configIgnoredFlags[flag.Name] {
+			continue
+		}
+		fmt.Fprintf(w, "--%s\t%s\t%s\n", flag.Name, formatFlagValue(ctx.FlagValue(flag)), resolver.source(ctx, flag))
+	}
+	return w.Flush()
+}
+
+// formatFlagValue formats the value of a flag, quoting strings.
+func formatFlagValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		quoted := make([]string, 0, len(v))
+		for _, s := range v {
+			quoted = append(quoted, fmt.Sprintf("%q", s))
+		}
+		return strings.Join(quoted, ",")
+	case fmt.Stringer:
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprint(value)
+}
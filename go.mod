@@ -3,6 +3,7 @@ module github.com/Broderick-Westrope/amalgo
 go 1.23.3
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/MakeNowJust/heredoc v1.0.0
 	github.com/alecthomas/kong v1.6.1
 	github.com/fatih/color v1.18.0
@@ -19,5 +20,5 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.13.1
 	golang.org/x/sys v0.25.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,318 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Broderick-Westrope/amalgo/pkg/filter"
+)
+
+// FileClass classifies files which are usually not written by hand
+type FileClass string
+
+const (
+	FileClassGenerated FileClass = "generated"
+	FileClassVendored  FileClass = "vendored"
+	FileClassLockfile  FileClass = "lockfile"
+)
+
+// Classification describes the class of a file and what decided it
+type Classification struct {
+	Class  FileClass // Empty when the file is not classified
+	Reason string
+}
+
+// lockfileNames are the names of the lock files written by package managers.
+var lockfileNames = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"Gemfile.lock":      true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+}
+
+// vendoredDirs are the names of the directories containing vendored code.
+var vendoredDirs = []string{"vendor", "third_party"}
+
+// generatedSuffixes are the file name suffixes of generated code, along with their generator. Longer
+// suffixes are listed first.
+var generatedSuffixes = []struct {
+	suffix    string
+	generator string
+}{
+	{"_grpc.pb.go", "gRPC"},
+	{"_pb2_grpc.py", "gRPC"},
+	{"_grpc_pb.js", "gRPC"},
+	{".pb.gw.go", "gRPC gateway"},
+	{".pb.go", "protobuf"},
+	{"_pb2.py", "protobuf"},
+	{"_pb2.pyi", "protobuf"},
+	{"_pb.js", "protobuf"},
+	{"_pb.d.ts", "protobuf"},
+	{".pb.cc", "protobuf"},
+	{".pb.h", "protobuf"},
+	{".min.js", "minifier"},
+	{".min.css", "minifier"},
+}
+
+// generatedHeader matches the header of generated code, following the Go convention
+// (see https://go.dev/s/generatedcode).
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+const (
+	// classifySampleSize is the number of bytes read from the start of a file to classify it.
+	classifySampleSize = 8 * 1024
+	// minifiedLineLength is the average line length above which scripts and styles are considered minified.
+	minifiedLineLength = 110
+)
+
+// fileClassifier classifies the files of a directory while it is being walked.
+type fileClassifier struct {
+	attributes *gitattributesMatcher
+	root       string // Directory which the locations of files are relative to (eg. "vendor/lib/lib.go")
+}
+
+// newFileClassifier creates a classifier for the given directory, loading the .gitattributes files
+// which apply to it (see newGitattributesMatcher). Files are located within the repository, or within
+// the parent of the directory outside a repository, so that the directory itself may be "vendor".
+func newFileClassifier(dir string) (*fileClassifier, error) {
+	attributes, err := newGitattributesMatcher(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, gitDir := findRepository(dir)
+	if gitDir == "" {
+		root = filepath.Dir(dir)
+	}
+	return &fileClassifier{attributes: attributes, root: root}, nil
+}
+
+// classify returns the class of a file. The linguist-vendored and linguist-generated attributes take
+// precedence over the name, location, and contents of the file.
+func (c *fileClassifier) classify(path string) (Classification, error) {
+	vendored, vendoredRule := c.attributes.lookup(path, "linguist-vendored")
+	if vendored == "true" {
+		return Classification{Class: FileClassVendored, Reason: vendoredRule}, nil
+	}
+	generated, generatedRule := c.attributes.lookup(path, "linguist-generated")
+	if generated == "true" {
+		return Classification{Class: FileClassGenerated, Reason: generatedRule}, nil
+	}
+
+	name := filepath.Base(path)
+	if lockfileNames[name] {
+		return Classification{Class: FileClassLockfile, Reason: "named " + name}, nil
+	}
+	if relPath, err := filepath.Rel(c.root, path); err == nil && vendored != "false" {
+		components := strings.Split(filepath.ToSlash(relPath), "/")
+		for _, component := range components[:len(components)-1] {
+			for _, dir := range vendoredDirs {
+				if component == dir {
+					return Classification{Class: FileClassVendored, Reason: fmt.Sprintf("within %s/", dir)}, nil
+				}
+			}
+		}
+	}
+	if generated == "false" {
+		return Classification{}, nil
+	}
+	for _, s := range generatedSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return Classification{Class: FileClassGenerated, Reason: fmt.Sprintf("%s output named *%s", s.generator, s.suffix)}, nil
+		}
+	}
+
+	reason, err := classifyContents(path)
+	if err != nil {
+		return Classification{}, fmt.Errorf("classifying file %q: %w", path, err)
+	} else if reason != "" {
+		return Classification{Class: FileClassGenerated, Reason: reason}, nil
+	}
+	return Classification{}, nil
+}
+
+// classifyContents returns the reason a file is considered generated based on the start of its contents:
+// a "Code generated ... DO NOT EDIT." header, or a script or stylesheet with very long lines.
+func classifyContents(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	sample := make([]byte, classifySampleSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	sample = sample[:n]
+
+	lines := strings.Split(string(sample), "\n")
+	for _, line := range lines {
+		if generatedHeader.MatchString(strings.TrimSuffix(line, "\r")) {
+			return `"Code generated ... DO NOT EDIT." header`, nil
+		}
+	}
+
+	switch filepath.Ext(path) {
+	case ".js", ".mjs", ".cjs", ".css":
+		if len(sample)/len(lines) > minifiedLineLength {
+			return "minified", nil
+		}
+	}
+	return "", nil
+}
+
+// gitattributesName is the name of the files discovered when classifying files.
+const gitattributesName = ".gitattributes"
+
+// gitattributesMatcher tracks the attributes of a git repository while it is being walked. As with git,
+// the attributes of each .gitattributes file are anchored to the directory containing it, those deeper in
+// the repository take precedence, and the repository's info/attributes file takes precedence over all.
+type gitattributesMatcher struct {
+	root   string // Directory at the root of the repository (or of the walk, outside a repository)
+	rules  []attributeRule
+	info   []attributeRule // Rules of the info/attributes file
+	loaded map[string]bool // Directories whose .gitattributes files have been loaded
+}
+
+// attributeRule is a line of a gitattributes file, setting attributes for the paths matching its pattern.
+type attributeRule struct {
+	filter *filter.Filter
+	source string
+	lineNo int
+	line   string
+	attrs  map[string]string // Values of the attributes, where unspecified attributes are empty
+}
+
+// newGitattributesMatcher loads the attributes which apply to the given directory, excluding those in
+// .gitattributes files within the directory itself (see addDir).
+func newGitattributesMatcher(dir string) (*gitattributesMatcher, error) {
+	root, gitDir := findRepository(dir)
+	m := &gitattributesMatcher{root: root, loaded: make(map[string]bool)}
+
+	if gitDir != "" {
+		var err error
+		m.info, err = readGitattributesFile(filepath.Join(gitDir, "info", "attributes"), "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Collect the .gitattributes files of the directories above the walked directory.
+	ancestors := make([]string, 0)
+	for current := dir; current != root; {
+		current = filepath.Dir(current)
+		ancestors = append(ancestors, current)
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if err := m.addDir(ancestors[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addDir loads the .gitattributes file of a directory, if it has one. Its rules take precedence over
+// those already loaded but only apply within the directory.
+func (m *gitattributesMatcher) addDir(dir string) error {
+	if m.loaded[dir] {
+		return nil
+	}
+	m.loaded[dir] = true
+
+	base, err := filepath.Rel(m.root, dir)
+	if err != nil {
+		return fmt.Errorf("getting relative path between %q and %q: %w", m.root, dir, err)
+	}
+	if base == "." {
+		base = ""
+	}
+	rules, err := readGitattributesFile(filepath.Join(dir, gitattributesName), filepath.ToSlash(base))
+	if err != nil {
+		return err
+	}
+	m.rules = append(m.rules, rules...)
+	return nil
+}
+
+// lookup returns the value of an attribute for the file at the given path, along with the location of the
+// rule which set it. The value is "true" or "false" for set and unset attributes, and empty if unspecified.
+func (m *gitattributesMatcher) lookup(path, attr string) (string, string) {
+	relPath, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return "", ""
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	value, rule := "", ""
+	for _, rules := range [][]attributeRule{m.rules, m.info} {
+		for _, r := range rules {
+			v, ok := r.attrs[attr]
+			if !ok || !r.filter.MatchesPath(relPath) {
+				continue
+			}
+			value = v
+			rule = fmt.Sprintf("%s:%d %q", displayPath(r.source), r.lineNo, r.line)
+		}
+	}
+	return value, rule
+}
+
+// readGitattributesFile reads the rules of a gitattributes file which lives in the given base directory
+// (relative to the root of the repository). A missing file has no rules.
+func readGitattributesFile(path, base string) ([]attributeRule, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading gitattributes file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	rules := make([]attributeRule, 0)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		// Negative patterns are forbidden in gitattributes files, and are ignored as in git.
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+
+		f, err := filter.CompileGitignorePatterns(path, base, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("compiling pattern from gitattributes file %q on line %d: %w", path, lineNo, err)
+		}
+		rule := attributeRule{filter: f, source: path, lineNo: lineNo, line: line, attrs: make(map[string]string)}
+		for _, attr := range fields[1:] {
+			switch {
+			case strings.HasPrefix(attr, "-"):
+				rule.attrs[attr[1:]] = "false"
+			case strings.HasPrefix(attr, "!"):
+				rule.attrs[attr[1:]] = ""
+			default:
+				name, value, found := strings.Cut(attr, "=")
+				if !found {
+					value = "true"
+				}
+				rule.attrs[name] = value
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading gitattributes file %q: %w", path, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileClassifierClassify(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		".gitattributes": heredoc.Doc(`
+			docs/** linguist-generated
+			third_party/** -linguist-vendored
+			# gen/** linguist-generated
+			!keep.go linguist-generated
+			schema/*.go -linguist-generated
+		`),
+		"main.go":                      "package main\n",
+		"kind_string.go":               "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\n\npackage main\n",
+		"late_header.go":               "// Copyright 2026.\n\n// Code generated by hand. DO NOT EDIT.\n\npackage main\n",
+		"not_header.go":                "// Code generated by hand. Do edit.\npackage main\n",
+		"api/api.pb.go":                "package api\n",
+		"api/api_grpc.pb.go":           "package api\n",
+		"api/api_pb2.py":               "\n",
+		"web/app.min.js":               "var a = 1;\n",
+		"web/bundle.js":                strings.Repeat("var a=1;", 50) + "\n",
+		"web/app.js":                   "var a = 1;\nvar b = 2;\n",
+		"vendor/lib/lib.go":            "package lib\n",
+		"lib/vendor.go":                "package lib\n",
+		"third_party/lib/lib.go":       "package lib\n",
+		"go.sum":                       "example.com/x v1.0.0 h1:abc=\n",
+		"web/package-lock.json":        "{}\n",
+		"docs/guide.md":                "# Guide\n",
+		"schema/schema.go":             "// Code generated by hand. DO NOT EDIT.\n\npackage schema\n",
+		"schema/nested/.gitattributes": "*.md linguist-vendored\n",
+		"schema/nested/notes.md":       "notes\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	tests := map[string]struct {
+		relPath    string
+		wantClass  FileClass
+		wantReason string
+	}{
+		"plain file": {relPath: "main.go"},
+		"go header": {
+			relPath:    "kind_string.go",
+			wantClass:  FileClassGenerated,
+			wantReason: `"Code generated ... DO NOT EDIT." header`,
+		},
+		"go header after a comment": {
+			relPath:    "late_header.go",
+			wantClass:  FileClassGenerated,
+			wantReason: `"Code generated ... DO NOT EDIT." header`,
+		},
+		"similar header": {relPath: "not_header.go"},
+		"protobuf output": {
+			relPath:    "api/api.pb.go",
+			wantClass:  FileClassGenerated,
+			wantReason: "protobuf output named *.pb.go",
+		},
+		"grpc output": {
+			relPath:    "api/api_grpc.pb.go",
+			wantClass:  FileClassGenerated,
+			wantReason: "gRPC output named *_grpc.pb.go",
+		},
+		"python protobuf output": {
+			relPath:    "api/api_pb2.py",
+			wantClass:  FileClassGenerated,
+			wantReason: "protobuf output named *_pb2.py",
+		},
+		"minified name": {
+			relPath:    "web/app.min.js",
+			wantClass:  FileClassGenerated,
+			wantReason: "minifier output named *.min.js",
+		},
+		"minified contents": {
+			relPath:    "web/bundle.js",
+			wantClass:  FileClassGenerated,
+			wantReason: "minified",
+		},
+		"script": {relPath: "web/app.js"},
+		"vendor directory": {
+			relPath:    "vendor/lib/lib.go",
+			wantClass:  FileClassVendored,
+			wantReason: "within vendor/",
+		},
+		"vendor file name": {relPath: "lib/vendor.go"},
+		"unset vendored":   {relPath: "third_party/lib/lib.go"},
+		"go lockfile": {
+			relPath:    "go.sum",
+			wantClass:  FileClassLockfile,
+			wantReason: "named go.sum",
+		},
+		"npm lockfile": {
+			relPath:    "web/package-lock.json",
+			wantClass:  FileClassLockfile,
+			wantReason: "named package-lock.json",
+		},
+		"set generated": {
+			relPath:    "docs/guide.md",
+			wantClass:  FileClassGenerated,
+			wantReason: `.gitattributes:1 "docs/** linguist-generated"`,
+		},
+		"unset generated": {relPath: "schema/schema.go"},
+		"nested attributes": {
+			relPath:    "schema/nested/notes.md",
+			wantClass:  FileClassVendored,
+			wantReason: `.gitattributes:1 "*.md linguist-vendored"`,
+		},
+	}
+
+	c, err := newFileClassifier(tmpDir)
+	require.NoError(t, err)
+	require.NoError(t, c.attributes.addDir(tmpDir))
+	require.NoError(t, c.attributes.addDir(filepath.Join(tmpDir, "schema", "nested")))
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.classify(filepath.Join(tmpDir, tt.relPath))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantClass, got.Class)
+			if tt.wantReason != "" {
+				assert.True(t, strings.HasSuffix(got.Reason, tt.wantReason), "want reason ending %q, got %q", tt.wantReason, got.Reason)
+			}
+		})
+	}
+}
+
+func TestFileClassifierVendoredRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, path := range []string{"vendor/lib/lib.go", "repo/vendor/lib/lib.go", "third_party/repo/main.go"} {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte("package lib\n"), 0644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "repo", ".git"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "third_party", "repo", ".git"), 0755))
+
+	tests := map[string]struct {
+		dir       string
+		relPath   string
+		wantClass FileClass
+	}{
+		"vendor directory outside a repository": {
+			dir:       "vendor",
+			relPath:   "vendor/lib/lib.go",
+			wantClass: FileClassVendored,
+		},
+		"vendor directory within a repository": {
+			dir:       "repo/vendor/lib",
+			relPath:   "repo/vendor/lib/lib.go",
+			wantClass: FileClassVendored,
+		},
+		"repository within a vendor directory": {
+			dir:     "third_party/repo",
+			relPath: "third_party/repo/main.go",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := newFileClassifier(filepath.Join(tmpDir, tt.dir))
+			require.NoError(t, err)
+			got, err := c.classify(filepath.Join(tmpDir, tt.relPath))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantClass, got.Class)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileNames are the names of the config files discovered from the analyzed directory upward, in
+// order of preference.
+var ConfigFileNames = []string{".amalgo.yaml", ".amalgo.yml", "amalgo.toml"}
+
+// configProfilesKey is the key of the named profiles within a config file.
+const configProfilesKey = "profiles"
+
+// Config holds the settings of a config file. Settings are keyed by the name of their flag (eg. "no-dump"),
+// with values as strings or lists of strings.
+type Config struct {
+	Path     string
+	Settings map[string]any
+	Profiles map[string]map[string]any
+}
+
+// FindConfig searches the directory and its parents for a config file, returning its path or an empty
+// string if there is none. If a file is provided, the search starts from its parent directory.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("getting absolute path for %q: %w", dir, err)
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for current := dir; ; current = filepath.Dir(current) {
+		found := make([]string, 0, 1)
+		for _, name := range ConfigFileNames {
+			path := filepath.Join(current, name)
+			if _, err := os.Stat(path); err == nil {
+				found = append(found, path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("describing config file %q: %w", path, err)
+			}
+		}
+		switch {
+		case len(found) > 1:
+			return "", fmt.Errorf("found multiple config files in %q: %s", current, strings.Join(found, ", "))
+		case len(found) == 1:
+			return found[0], nil
+		case current == filepath.Dir(current):
+			return "", nil
+		}
+	}
+}
+
+// LoadConfig reads a YAML or TOML config file, depending on its extension. Keys may be written in
+// kebab-case or snake_case.
+func LoadConfig(path string) (*Config, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %q: %w", path, err)
+	}
+
+	raw := make(map[string]any)
+	if filepath.Ext(path) == ".toml" {
+		err = toml.Unmarshal(bs, &raw)
+	} else {
+		err = yaml.Unmarshal(bs, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
+	}
+
+	c := &Config{Path: path, Profiles: make(map[string]map[string]any)}
+	profiles, ok := raw[configProfilesKey]
+	delete(raw, configProfilesKey)
+	c.Settings, err = normalizeSettings(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
+	}
+	if !ok {
+		return c, nil
+	}
+
+	profileMap, ok := profiles.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("parsing config file %q: %q must be a map of profile names to settings", path, configProfilesKey)
+	}
+	for name, settings := range profileMap {
+		settingsMap, ok := settings.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("parsing config file %q: profile %q must be a map of settings", path, name)
+		}
+		c.Profiles[name], err = normalizeSettings(settingsMap)
+		if err != nil {
+			return nil, fmt.Errorf("parsing config file %q: profile %q: %w", path, name, err)
+		}
+	}
+	return c, nil
+}
+
+// Resolve returns the settings of the given profile merged over the top-level settings, along with the
+// profile each setting was taken from (which is empty for top-level settings).
+func (c *Config) Resolve(profile string) (map[string]any, map[string]string, error) {
+	settings := make(map[string]any, len(c.Settings))
+	sources := make(map[string]string, len(c.Settings))
+	for key, value := range c.Settings {
+		settings[key] = value
+		sources[key] = ""
+	}
+	if profile == "" {
+		return settings, sources, nil
+	}
+
+	profileSettings, ok := c.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return nil, nil, fmt.Errorf("profile %q not found in config file %q (available: %s)",
+			profile, c.Path, strings.Join(names, ", "))
+	}
+	for key, value := range profileSettings {
+		settings[key] = value
+		sources[key] = profile
+	}
+	return settings, sources, nil
+}
+
+// normalizeSettings converts the keys of the settings to kebab-case, and their values to strings or
+// lists of strings.
+func normalizeSettings(raw map[string]any) (map[string]any, error) {
+	settings := make(map[string]any, len(raw))
+	for key, value := range raw {
+		key = strings.ReplaceAll(key, "_", "-")
+		if _, exists := settings[key]; exists {
+			return nil, fmt.Errorf("setting %q is repeated", key)
+		}
+
+		if list, ok := value.([]any); ok {
+			values := make([]any, 0, len(list))
+			for _, v := range list {
+				s, err := settingString(v)
+				if err != nil {
+					return nil, fmt.Errorf("setting %q: %w", key, err)
+				}
+				values = append(values, s)
+			}
+			settings[key] = values
+			continue
+		}
+
+		s, err := settingString(value)
+		if err != nil {
+			return nil, fmt.Errorf("setting %q: %w", key, err)
+		}
+		settings[key] = s
+	}
+	return settings, nil
+}
+
+// settingString formats a scalar value as it would be given on the command-line.
+func settingString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		// Dates without a time (eg. "2026-09-01") are decoded as midnight in UTC.
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format(time.DateOnly), nil
+		}
+		return v.Format(time.RFC3339), nil
+	case map[string]any, []any, nil:
+		return "", fmt.Errorf("expected a value or a list of values")
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := map[string]struct {
+		name         string
+		content      string
+		wantSettings map[string]any
+		wantProfiles map[string]map[string]any
+		wantErr      bool
+	}{
+		"yaml": {
+			name: ".amalgo.yaml",
+			content: `filter: "*.go"
+no_tree: true
+max-lines: 100
+modified-since: 2026-09-01
+exclude-lang: [markdown, yaml]
+profiles:
+  review:
+    stdout: true
+`,
+			wantSettings: map[string]any{
+				"filter":         "*.go",
+				"no-tree":        "true",
+				"max-lines":      "100",
+				"modified-since": "2026-09-01",
+				"exclude-lang":   []any{"markdown", "yaml"},
+			},
+			wantProfiles: map[string]map[string]any{"review": {"stdout": "true"}},
+		},
+		"toml": {
+			name: "amalgo.toml",
+			content: `filter = ["*.go", "*.md"]
+modified_since = 2026-09-01T10:30:00Z
+
+[profiles.ci]
+format = "json"
+`,
+			wantSettings: map[string]any{
+				"filter":         []any{"*.go", "*.md"},
+				"modified-since": "2026-09-01T10:30:00Z",
+			},
+			wantProfiles: map[string]map[string]any{"ci": {"format": "json"}},
+		},
+		"repeated setting": {
+			name:    ".amalgo.yaml",
+			content: "no-tree: true\nno_tree: false\n",
+			wantErr: true,
+		},
+		"nested setting": {
+			name:    ".amalgo.yaml",
+			content: "filter:\n  include: \"*.go\"\n",
+			wantErr: true,
+		},
+		"invalid profiles": {
+			name:    ".amalgo.yaml",
+			content: "profiles: [review]\n",
+			wantErr: true,
+		},
+		"invalid syntax": {
+			name:    "amalgo.toml",
+			content: "filter = \n",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			got, err := LoadConfig(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSettings, got.Settings)
+			assert.Equal(t, tt.wantProfiles, got.Profiles)
+		})
+	}
+}
+
+func TestConfigResolve(t *testing.T) {
+	config := &Config{
+		Path:     ".amalgo.yaml",
+		Settings: map[string]any{"filter": "*.go", "no-tree": "true"},
+		Profiles: map[string]map[string]any{"review": {"filter": []any{"*.go", "*.md"}}},
+	}
+
+	settings, sources, err := config.Resolve("review")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"filter": []any{"*.go", "*.md"}, "no-tree": "true"}, settings)
+	assert.Equal(t, map[string]string{"filter": "review", "no-tree": ""}, sources)
+
+	_, _, err = config.Resolve("missing")
+	assert.ErrorContains(t, err, `profile "missing" not found`)
+}
+
+func TestFindConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "amalgo.toml"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", ".amalgo.yaml"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(nested, "main.go"), nil, 0644))
+
+	got, err := FindConfig(nested)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "a", ".amalgo.yaml"), got)
+
+	got, err = FindConfig(filepath.Join(nested, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "a", ".amalgo.yaml"), got)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", ".amalgo.yml"), nil, 0644))
+	_, err = FindConfig(nested)
+	assert.ErrorContains(t, err, "found multiple config files")
+}
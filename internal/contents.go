@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// contentMatcher decides whether a file is collected based on its contents.
+type contentMatcher struct {
+	contains    []*regexp.Regexp
+	notContains []*regexp.Regexp
+}
+
+// newContentMatcher compiles the content patterns. As with grep, '^' and '$' match at the start and end of
+// each line. A nil matcher is returned when there are no patterns.
+func newContentMatcher(contains, notContains []string) (*contentMatcher, error) {
+	if len(contains) == 0 && len(notContains) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	m := new(contentMatcher)
+	m.contains, err = compileContentPatterns(contains)
+	if err != nil {
+		return nil, err
+	}
+	m.notContains, err = compileContentPatterns(notContains)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func compileContentPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		// The pattern is compiled without the flags first, so that errors refer to it as given.
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		result = append(result, regexp.MustCompile("(?m)"+pattern))
+	}
+	return result, nil
+}
+
+// check returns the reason a file is excluded by its contents, or an empty string if it is not. The file must
+// contain a match for any of the contains patterns and none of the not-contains patterns. Files are streamed
+// rather than loaded into memory, and each pattern stops reading at its first match.
+func (m *contentMatcher) check(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	if len(m.contains) > 0 {
+		found := false
+		for _, re := range m.contains {
+			found, err = matchReader(file, re)
+			if err != nil || found {
+				break
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("matching contents of file %q: %w", path, err)
+		} else if !found {
+			return "contents do not match any '--contains' pattern", nil
+		}
+	}
+
+	for _, re := range m.notContains {
+		found, err := matchReader(file, re)
+		if err != nil {
+			return "", fmt.Errorf("matching contents of file %q: %w", path, err)
+		} else if found {
+			return fmt.Sprintf("contents match '--not-contains' pattern %q", strings.TrimPrefix(re.String(), "(?m)")), nil
+		}
+	}
+	return "", nil
+}
+
+// matchReader reports whether the regular expression matches the contents of the file from its start.
+func matchReader(file *os.File, re *regexp.Regexp) (bool, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	r := &errReader{r: bufio.NewReader(file)}
+	found := re.MatchReader(r)
+	return found, r.err
+}
+
+// errReader records the first error other than io.EOF, as regexp.MatchReader treats any error as the end
+// of the input.
+type errReader struct {
+	r   io.RuneReader
+	err error
+}
+
+func (r *errReader) ReadRune() (rune, int, error) {
+	ch, size, err := r.r.ReadRune()
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return ch, size, err
+}
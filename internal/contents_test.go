@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentMatcherCheck(t *testing.T) {
+	tests := map[string]struct {
+		content     string
+		contains    []string
+		notContains []string
+		wantReason  string
+	}{
+		"matches contains": {
+			content:  "package a\n\nfunc UserHandler() {}\n",
+			contains: []string{"func .*Handler"},
+		},
+		"matches any contains": {
+			content:  "package a\n",
+			contains: []string{"func", "^package"},
+		},
+		"does not match contains": {
+			content:    "package a\n",
+			contains:   []string{"func"},
+			wantReason: "contents do not match any '--contains' pattern",
+		},
+		"anchors match lines": {
+			content:    "package a\n// func main\n",
+			contains:   []string{"^func"},
+			wantReason: "contents do not match any '--contains' pattern",
+		},
+		"matches not contains": {
+			content:     "// Code generated by x. DO NOT EDIT.\npackage a\n",
+			notContains: []string{"^// Code generated .* DO NOT EDIT\\.$"},
+			wantReason:  `contents match '--not-contains' pattern "^// Code generated .* DO NOT EDIT\\.$"`,
+		},
+		"matches contains and not contains": {
+			content:     "func Handler() {}\n// TODO\n",
+			contains:    []string{"Handler"},
+			notContains: []string{"TODO"},
+			wantReason:  `contents match '--not-contains' pattern "TODO"`,
+		},
+		"match beyond read buffer": {
+			content:  strings.Repeat("filler\n", 10_000) + "needle\n",
+			contains: []string{"^needle$"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.go")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			m, err := newContentMatcher(tt.contains, tt.notContains)
+			require.NoError(t, err)
+			reason, err := m.check(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
+
+func TestNewContentMatcher(t *testing.T) {
+	m, err := newContentMatcher(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = newContentMatcher([]string{"("}, nil)
+	assert.ErrorContains(t, err, "pattern \"(\"")
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Broderick-Westrope/amalgo/internal/parser"
+)
+
+// DeclarationsExported selects the exported declarations when dumping declarations
+const DeclarationsExported = "exported"
+
+// declarationLines is a range of lines to keep, starting at 1 and inclusive.
+type declarationLines struct {
+	start, end int
+}
+
+// sliceDeclarations keeps only the header of a file (eg. the package clause and imports) and the
+// top-level declarations selected by the patterns, which are either "exported" or name globs
+// (eg. "*Handler"). Omitted lines are replaced with a comment stating how many were removed.
+// It returns the sliced content and the number of lines omitted.
+func sliceDeclarations(content []byte, outline *parser.FileOutline, patterns []string) (string, int) {
+	lineOf := func(offset int) int {
+		return bytes.Count(content[:min(offset, len(content))], []byte("\n")) + 1
+	}
+
+	keep := make([]declarationLines, 0)
+	if outline.Header > 0 {
+		keep = append(keep, declarationLines{1, lineOf(outline.Header)})
+	}
+	for _, symbol := range outline.Symbols {
+		if !selectDeclaration(symbol, patterns) {
+			continue
+		}
+		// Members of enums may be declared separately from their type.
+		for _, s := range append([]*parser.Symbol{symbol}, symbol.Children...) {
+			if s.End > 0 {
+				keep = append(keep, declarationLines{lineOf(s.Start), lineOf(s.End)})
+			}
+		}
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	kept := make([]bool, len(lines))
+	for _, r := range keep {
+		for i := r.start; i <= r.end && i <= len(lines); i++ {
+			kept[i-1] = true
+		}
+	}
+
+	var sb strings.Builder
+	omitted := 0
+	for i := 0; i < len(lines); {
+		if kept[i] {
+			sb.WriteString(lines[i])
+			i++
+			continue
+		}
+
+		// Blank lines surrounding an omitted region are kept.
+		j := i
+		for j < len(lines) && !kept[j] {
+			j++
+		}
+		start, end := i, j
+		for start < end && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		sb.WriteString(strings.Join(lines[i:start], ""))
+		if end-start == 1 {
+			sb.WriteString("// ... 1 line omitted\n")
+		} else if end > start {
+			sb.WriteString(fmt.Sprintf("// ... %d lines omitted\n", end-start))
+		}
+		omitted += end - start
+		sb.WriteString(strings.Join(lines[end:j], ""))
+		i = j
+	}
+	return sb.String(), omitted
+}
+
+// selectDeclaration reports whether a top-level symbol is selected by any of the patterns.
+// Methods (eg. Store.Add) are matched by both their qualified name and the name of the method.
+func selectDeclaration(symbol *parser.Symbol, patterns []string) bool {
+	name := plainSymbolName(symbol.Name)
+	for _, pattern := range patterns {
+		if pattern == DeclarationsExported {
+			if isExportedSymbol(name) {
+				return true
+			}
+			continue
+		}
+
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			if ok, _ := path.Match(pattern, name[i+1:]); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isExportedSymbol reports whether each part of a symbol name starts with an upper-case letter,
+// following the Go rules for exported identifiers (eg. Store.Add but not store.Add or Store.add).
+func isExportedSymbol(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		r, _ := utf8.DecodeRuneInString(part)
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
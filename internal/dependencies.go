@@ -0,0 +1,421 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type DependencyDiagram string
+
+const (
+	DependencyDiagramNone    = "none"
+	DependencyDiagramDOT     = "dot"
+	DependencyDiagramMermaid = "mermaid"
+)
+
+// maxFanInPackages is the number of packages highlighted as having the highest fan-in.
+const maxFanInPackages = 5
+
+// DependencyGraph describes the imports between the Go packages of the selected files.
+// Only imports of packages within the same module are included.
+type DependencyGraph struct {
+	Packages []*PackageNode // Packages sorted by import path
+	Cycles   [][]string     // Import paths of packages which import each other, directly or indirectly
+}
+
+// PackageNode represents a Go package within a dependency graph
+type PackageNode struct {
+	ImportPath string
+	Name       string   // Package name, if the package contains selected files
+	Imports    []string // Import paths of imported packages from the same module
+	ImportedBy []string // Import paths of packages from the same module which import this package
+}
+
+// GenerateDependencyGraph builds the package import graph from the selected Go files.
+// Packages are identified using the module path from the nearest go.mod file of each file.
+// Test files are ignored since their imports do not form part of the package graph. Files which cannot
+// be read are collected by the warnings and left out of the graph.
+func GenerateDependencyGraph(paths []PathInfo, warnings *Warnings) (*DependencyGraph, error) {
+	nodes := make(map[string]*PackageNode)
+	getNode := func(importPath string) *PackageNode {
+		node, ok := nodes[importPath]
+		if !ok {
+			node = &PackageNode{ImportPath: importPath}
+			nodes[importPath] = node
+		}
+		return node
+	}
+
+	modules := newModuleResolver()
+	imports := make(map[string]map[string]struct{})
+	for _, path := range paths {
+		if path.IsDir || path.Link != "" || filepath.Ext(path.Path) != ".go" || strings.HasSuffix(path.Path, "_test.go") {
+			continue
+		}
+
+		importPath, err := modules.importPath(filepath.Dir(path.Path))
+		if err != nil {
+			return nil, fmt.Errorf("resolving import path of %q: %w", path.Path, err)
+		}
+		if importPath == "" {
+			// The file is not part of a module.
+			continue
+		}
+
+		content, err := os.ReadFile(path.Path)
+		if err != nil {
+			if err := warnings.Add(path.Path, path.RelativePath, err); err != nil {
+				return nil, fmt.Errorf("reading file %q: %w", path.Path, err)
+			}
+			continue
+		}
+		// Syntax errors still produce the imports that could be parsed.
+		file, _ := parser.ParseFile(token.NewFileSet(), path.Path, content, parser.ImportsOnly)
+		if file == nil {
+			continue
+		}
+
+		node := getNode(importPath)
+		node.Name = file.Name.Name
+		if imports[importPath] == nil {
+			imports[importPath] = make(map[string]struct{})
+		}
+		for _, spec := range file.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			imports[importPath][imported] = struct{}{}
+		}
+	}
+
+	for importPath, imported := range imports {
+		node := nodes[importPath]
+		for dep := range imported {
+			if !modules.contains(dep) || dep == importPath {
+				continue
+			}
+			node.Imports = append(node.Imports, dep)
+			depNode := getNode(dep)
+			depNode.ImportedBy = append(depNode.ImportedBy, importPath)
+		}
+	}
+
+	graph := &DependencyGraph{Packages: make([]*PackageNode, 0, len(nodes))}
+	for _, node := range nodes {
+		slices.Sort(node.Imports)
+		slices.Sort(node.ImportedBy)
+		graph.Packages = append(graph.Packages, node)
+	}
+	slices.SortFunc(graph.Packages, func(a, b *PackageNode) int {
+		return strings.Compare(a.ImportPath, b.ImportPath)
+	})
+	graph.Cycles = findCycles(graph.Packages)
+	return graph, nil
+}
+
+// HighestFanIn returns the packages imported by the most other packages, in descending order.
+// Packages which are not imported are never included.
+func (g *DependencyGraph) HighestFanIn() []*PackageNode {
+	result := make([]*PackageNode, 0)
+	for _, node := range g.Packages {
+		if len(node.ImportedBy) > 0 {
+			result = append(result, node)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b *PackageNode) int {
+		return len(b.ImportedBy) - len(a.ImportedBy)
+	})
+	if len(result) > maxFanInPackages {
+		result = result[:maxFanInPackages]
+	}
+	return result
+}
+
+// findCycles returns the strongly connected components of the graph which contain a cycle,
+// found using Tarjan's algorithm. Each component and the list of components are sorted.
+func findCycles(packages []*PackageNode) [][]string {
+	type state struct {
+		index, lowLink int
+		onStack        bool
+	}
+	nodes := make(map[string]*PackageNode, len(packages))
+	for _, node := range packages {
+		nodes[node.ImportPath] = node
+	}
+	states := make(map[string]*state)
+	stack := make([]string, 0)
+	cycles := make([][]string, 0)
+	index := 0
+
+	var connect func(importPath string)
+	connect = func(importPath string) {
+		s := &state{index: index, lowLink: index, onStack: true}
+		states[importPath] = s
+		index++
+		stack = append(stack, importPath)
+
+		for _, dep := range nodes[importPath].Imports {
+			depState, visited := states[dep]
+			if !visited {
+				connect(dep)
+				s.lowLink = min(s.lowLink, states[dep].lowLink)
+			} else if depState.onStack {
+				s.lowLink = min(s.lowLink, depState.index)
+			}
+		}
+
+		if s.lowLink != s.index {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			states[top].onStack = false
+			component = append(component, top)
+			if top == importPath {
+				break
+			}
+		}
+		if len(component) > 1 {
+			slices.Sort(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range packages {
+		if _, visited := states[node.ImportPath]; !visited {
+			connect(node.ImportPath)
+		}
+	}
+	slices.SortFunc(cycles, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return cycles
+}
+
+// moduleResolver finds the Go module containing a directory by searching for the nearest go.mod file.
+type moduleResolver struct {
+	dirs    map[string]string // Directories to the root directory of their module, or "" if none
+	modules map[string]string // Module root directories to module paths
+}
+
+func newModuleResolver() *moduleResolver {
+	return &moduleResolver{
+		dirs:    make(map[string]string),
+		modules: make(map[string]string),
+	}
+}
+
+// importPath returns the import path of the package in the given directory,
+// or an empty string if the directory is not within a module.
+func (r *moduleResolver) importPath(dir string) (string, error) {
+	root, err := r.moduleRoot(dir)
+	if err != nil || root == "" {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return r.modules[root], nil
+	}
+	return r.modules[root] + "/" + filepath.ToSlash(rel), nil
+}
+
+func (r *moduleResolver) moduleRoot(dir string) (string, error) {
+	if root, ok := r.dirs[dir]; ok {
+		return root, nil
+	}
+
+	root := ""
+	modulePath, err := readModulePath(filepath.Join(dir, "go.mod"))
+	switch {
+	case err == nil:
+		root = dir
+		r.modules[dir] = modulePath
+	case !os.IsNotExist(err):
+		return "", err
+	case filepath.Dir(dir) != dir:
+		root, err = r.moduleRoot(filepath.Dir(dir))
+		if err != nil {
+			return "", err
+		}
+	}
+	r.dirs[dir] = root
+	return root, nil
+}
+
+// contains reports whether the import path is a package of one of the discovered modules. The package must
+// be a directory of the module outside of any nested module, so that other major versions of the module
+// (eg. example.com/mod/v2) and nested modules (eg. example.com/mod/tools) are not included.
+func (r *moduleResolver) contains(importPath string) bool {
+	for root, modulePath := range r.modules {
+		rel, ok := strings.CutPrefix(importPath, modulePath)
+		if !ok || (rel != "" && rel[0] != '/') {
+			continue
+		}
+		dir := filepath.Join(root, filepath.FromSlash(rel))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if !hasNestedModule(root, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNestedModule reports whether a directory within a module root belongs to a nested module, which has
+// its own go.mod file in the directory or one of its parents below the root.
+func hasNestedModule(root, dir string) bool {
+	for current := dir; current != root && strings.HasPrefix(current, root); current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readModulePath returns the module path declared by the go.mod file at the given path.
+func readModulePath(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line, _, _ = strings.Cut(line, "//")
+		modulePath, found := strings.CutPrefix(line, "module")
+		if !found || modulePath == "" || (modulePath[0] != ' ' && modulePath[0] != '\t') {
+			continue
+		}
+		modulePath = strings.TrimSpace(modulePath)
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+		return modulePath, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module directive found in %q", path)
+}
+
+// writeDependencyGraph creates a textual representation of the dependency graph
+// as an adjacency list, followed by any cycles and the packages with the highest fan-in.
+func writeDependencyGraph(graph *DependencyGraph) string {
+	if len(graph.Packages) == 0 {
+		return "< no Go packages found >\n"
+	}
+
+	var sb strings.Builder
+	for _, node := range graph.Packages {
+		sb.WriteString(node.ImportPath)
+		if node.Name != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", node.Name))
+		}
+		sb.WriteString("\n")
+		if len(node.Imports) > 0 {
+			sb.WriteString(fmt.Sprintf("  imports: %s\n", strings.Join(node.Imports, ", ")))
+		}
+		if len(node.ImportedBy) > 0 {
+			sb.WriteString(fmt.Sprintf("  imported by: %s\n", strings.Join(node.ImportedBy, ", ")))
+		}
+	}
+
+	sb.WriteString("\nImport cycles:\n")
+	if len(graph.Cycles) == 0 {
+		sb.WriteString("  none\n")
+	}
+	for _, cycle := range graph.Cycles {
+		sb.WriteString(fmt.Sprintf("  %s\n", strings.Join(cycle, ", ")))
+	}
+
+	sb.WriteString("\nHighest fan-in:\n")
+	fanIn := graph.HighestFanIn()
+	if len(fanIn) == 0 {
+		sb.WriteString("  none\n")
+	}
+	for _, node := range fanIn {
+		sb.WriteString(fmt.Sprintf("  %s (imported by %d)\n", node.ImportPath, len(node.ImportedBy)))
+	}
+	return sb.String()
+}
+
+// writeDependencyDiagram creates a Graphviz DOT or Mermaid flowchart representation of the
+// dependency graph. Packages within import cycles and those with the highest fan-in are highlighted.
+func writeDependencyDiagram(graph *DependencyGraph, diagram DependencyDiagram) string {
+	inCycle := make(map[string]bool)
+	for _, cycle := range graph.Cycles {
+		for _, importPath := range cycle {
+			inCycle[importPath] = true
+		}
+	}
+	highFanIn := make(map[string]bool)
+	for _, node := range graph.HighestFanIn() {
+		highFanIn[node.ImportPath] = true
+	}
+
+	var sb strings.Builder
+	switch diagram {
+	case DependencyDiagramDOT:
+		sb.WriteString("digraph dependencies {\n")
+		for _, node := range graph.Packages {
+			attrs := make([]string, 0)
+			if inCycle[node.ImportPath] {
+				attrs = append(attrs, "color=red")
+			}
+			if highFanIn[node.ImportPath] {
+				attrs = append(attrs, "penwidth=2")
+			}
+			sb.WriteString(fmt.Sprintf("  %q", node.ImportPath))
+			if len(attrs) > 0 {
+				sb.WriteString(fmt.Sprintf(" [%s]", strings.Join(attrs, ", ")))
+			}
+			sb.WriteString(";\n")
+		}
+		for _, node := range graph.Packages {
+			for _, dep := range node.Imports {
+				sb.WriteString(fmt.Sprintf("  %q -> %q;\n", node.ImportPath, dep))
+			}
+		}
+		sb.WriteString("}\n")
+
+	case DependencyDiagramMermaid:
+		ids := make(map[string]string, len(graph.Packages))
+		sb.WriteString("flowchart LR\n")
+		for i, node := range graph.Packages {
+			ids[node.ImportPath] = fmt.Sprintf("p%d", i)
+			sb.WriteString(fmt.Sprintf("  %s[%q]\n", ids[node.ImportPath], node.ImportPath))
+		}
+		for _, node := range graph.Packages {
+			for _, dep := range node.Imports {
+				sb.WriteString(fmt.Sprintf("  %s --> %s\n", ids[node.ImportPath], ids[dep]))
+			}
+		}
+		for _, node := range graph.Packages {
+			if inCycle[node.ImportPath] {
+				sb.WriteString(fmt.Sprintf("  class %s cycle\n", ids[node.ImportPath]))
+			}
+			if highFanIn[node.ImportPath] {
+				sb.WriteString(fmt.Sprintf("  class %s fanin\n", ids[node.ImportPath]))
+			}
+		}
+		sb.WriteString("  classDef cycle stroke:#f00\n")
+		sb.WriteString("  classDef fanin stroke-width:3px\n")
+	}
+	return sb.String()
+}
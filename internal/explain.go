@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Explanation describes why a path within a traversed directory is included or excluded
+type Explanation struct {
+	RelativePath string // Relative to the parent of the traversed directory, as with PathInfo
+	IsDir        bool
+	Decision     Decision
+	ExcludedDir  string // Relative path of the excluded directory containing the path, if it decided
+}
+
+// ExplainDirectory explains the selection of each file within the directory, along with each directory
+// which is not traversed. If paths are given, only those paths (and the contents of directories) are explained.
+func ExplainDirectory(dir string, opts TraverseOptions, paths []string) ([]Explanation, error) {
+	s, basePath, err := newPathSelector(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &explainer{basePath: basePath, selector: s, explanations: make([]Explanation, 0)}
+	if len(paths) == 0 {
+		paths = []string{basePath}
+	}
+	for _, path := range paths {
+		if err := e.explainPath(path); err != nil {
+			return nil, err
+		}
+	}
+	return e.explanations, nil
+}
+
+// explainer collects the explanations for paths within a traversed directory.
+type explainer struct {
+	basePath     string
+	selector     *pathSelector
+	explanations []Explanation
+}
+
+func (e *explainer) add(path string, isDir bool, decision Decision, excludedDir string) error {
+	relPath, err := filepath.Rel(filepath.Dir(e.basePath), path)
+	if err != nil {
+		return fmt.Errorf("getting relative path between %q and %q: %w", e.basePath, path, err)
+	}
+	e.explanations = append(e.explanations, Explanation{
+		RelativePath: filepath.ToSlash(relPath),
+		IsDir:        isDir,
+		Decision:     decision,
+		ExcludedDir:  excludedDir,
+	})
+	return nil
+}
+
+// explainPath explains a path within the traversed directory. The directories which would be walked to reach
+// the path are selected first, as the path is excluded along with them.
+func (e *explainer) explainPath(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("getting absolute path for %q: %w", path, err)
+	}
+	relPath, err := filepath.Rel(e.basePath, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %q is not within directory %q", path, e.basePath)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("describing path %q: %w", path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	if relPath != "." {
+		decision, current, err := e.selector.selectAncestors(relPath)
+		if err != nil {
+			return err
+		} else if !decision.Included {
+			excludedDir, _ := filepath.Rel(filepath.Dir(e.basePath), current)
+			return e.add(absPath, info.IsDir(), decision, filepath.ToSlash(excludedDir))
+		}
+	}
+
+	err = walkSelection(e.basePath, absPath, e.selector, func(path, _ string, isDir bool, decision Decision) error {
+		return e.add(path, isDir, decision, "")
+	})
+	if err != nil {
+		return fmt.Errorf("walking path %q: %w", absPath, err)
+	}
+	return nil
+}
+
+// Reason describes the pattern or default which decided whether the path is included.
+func (e Explanation) Reason() string {
+	var reason string
+	pattern := e.Decision.Pattern
+	switch {
+	case e.Decision.Skipped != "":
+		reason = "skipped: " + e.Decision.Skipped
+	case e.Decision.Excluded != "":
+		reason = e.Decision.Excluded
+	case pattern == nil:
+		reason = "default: " + e.Decision.Default
+	case pattern.Source == "":
+		reason = fmt.Sprintf("filter pattern %d %q", pattern.LineNo, pattern.Line)
+	default:
+		reason = fmt.Sprintf("%s:%d %q", displayPath(pattern.Source), pattern.LineNo, pattern.Line)
+	}
+
+	if e.Decision.Included && e.Decision.Link != "" {
+		reason += fmt.Sprintf(", symbolic link to %s", e.Decision.Link)
+	}
+	if c := e.Decision.Classification; e.Decision.Included && c.Class != "" {
+		reason += fmt.Sprintf(", %s: %s", c.Class, c.Reason)
+	}
+	if e.ExcludedDir != "" {
+		reason += fmt.Sprintf(", in excluded directory %s/", e.ExcludedDir)
+	}
+	return reason
+}
+
+// displayPath returns the path relative to the working directory when it is within it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil || !filepath.IsAbs(path) {
+		return path
+	}
+	relPath, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Broderick-Westrope/amalgo/internal/parser"
+)
+
+const (
+	FocusRelationTarget = "target"
+	FocusRelationCallee = "callee"
+	FocusRelationCaller = "caller"
+)
+
+// Focus is the subset of the selected files which is relevant to a set of symbols
+type Focus struct {
+	Paths      []PathInfo // Files declaring the focused symbols, along with their parent directories
+	Snippets   []*FocusSnippet
+	References []*FileReferences // References to the symbols of the focused files, from any of the selected files
+}
+
+// FocusSnippet is the exact source of the declaration of a focused symbol
+type FocusSnippet struct {
+	Path      PathInfo
+	Symbol    *parser.Symbol
+	Relation  string // How the symbol relates to the targets: "target", "callee" or "caller"
+	Depth     int    // Number of references followed from the nearest target
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// focusNode is a symbol within the reference graph.
+type focusNode struct {
+	file     *FileReferences
+	symbol   *SymbolReferences
+	callers  []*focusNode // Symbols whose declarations reference this symbol
+	callees  []*focusNode // Symbols referenced by the declaration of this symbol
+	relation string
+	depth    int
+}
+
+// GenerateFocus resolves the target symbols (eg. "store.New", "Store.Add" or "New") and follows the
+// reference graph from them up to the given depth, in both directions: the symbols they reference
+// and the symbols referencing them. The declarations of the symbols found are sliced from their files.
+func GenerateFocus(paths []PathInfo, registry *parser.Registry, targets []string, depth int, warnings *Warnings) (*Focus, error) {
+	files, err := GenerateReferences(paths, registry, warnings)
+	if err != nil {
+		return nil, fmt.Errorf("generating references: %w", err)
+	}
+
+	nodes := make([]*focusNode, 0)
+	byPath := make(map[string][]*focusNode)
+	for _, file := range files {
+		for _, symbolRefs := range file.Symbols {
+			node := &focusNode{file: file, symbol: symbolRefs}
+			nodes = append(nodes, node)
+			byPath[file.Path.RelativePath] = append(byPath[file.Path.RelativePath], node)
+		}
+	}
+
+	// Link each referenced symbol to the symbol whose declaration contains the reference.
+	for _, node := range nodes {
+		seen := make(map[*focusNode]struct{})
+		for _, ref := range node.symbol.References {
+			caller := enclosingNode(byPath[ref.Path], ref.Line)
+			if _, ok := seen[caller]; caller == nil || caller == node || ok {
+				continue
+			}
+			seen[caller] = struct{}{}
+			node.callers = append(node.callers, caller)
+			caller.callees = append(caller.callees, node)
+		}
+	}
+
+	queue := make([]*focusNode, 0)
+	for _, target := range targets {
+		found := false
+		for _, node := range nodes {
+			if matchesFocus(target, node.file.Package, node.symbol.Symbol.Name) {
+				found = true
+				if node.relation == "" {
+					node.relation = FocusRelationTarget
+					queue = append(queue, node)
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no symbol matches %q", target)
+		}
+	}
+	walkFocus(queue, depth, FocusRelationCallee, func(n *focusNode) []*focusNode { return n.callees })
+	walkFocus(queue, depth, FocusRelationCaller, func(n *focusNode) []*focusNode { return n.callers })
+
+	focus := &Focus{Paths: make([]PathInfo, 0)}
+	included := make(map[string]struct{})
+	for _, file := range files {
+		focused := make([]*focusNode, 0)
+		for _, node := range byPath[file.Path.RelativePath] {
+			if node.relation != "" {
+				focused = append(focused, node)
+			}
+		}
+		if len(focused) == 0 {
+			continue
+		}
+		included[file.Path.RelativePath] = struct{}{}
+		focus.References = append(focus.References, file)
+
+		snippets, err := focusSnippets(file.Path, focused)
+		if err != nil {
+			return nil, err
+		}
+		focus.Snippets = append(focus.Snippets, snippets...)
+	}
+
+	// Keep the focused files and the directories containing them.
+	for _, path := range paths {
+		if !path.IsDir {
+			if _, ok := included[path.RelativePath]; ok {
+				focus.Paths = append(focus.Paths, path)
+			}
+			continue
+		}
+		for relPath := range included {
+			if strings.HasPrefix(relPath, path.RelativePath+"/") {
+				focus.Paths = append(focus.Paths, path)
+				break
+			}
+		}
+	}
+	return focus, nil
+}
+
+// matchesFocus reports whether a symbol is identified by the target, with or without its package name.
+func matchesFocus(target, pkg, name string) bool {
+	name = plainSymbolName(name)
+	return target == name || (pkg != "" && target == pkg+"."+name)
+}
+
+// enclosingNode returns the symbol with the narrowest declaration containing the line, if any.
+func enclosingNode(nodes []*focusNode, line int) *focusNode {
+	var result *focusNode
+	for _, node := range nodes {
+		symbol := node.symbol.Symbol
+		if symbol.EndLine == 0 || line < symbol.Line || line > symbol.EndLine {
+			continue
+		}
+		if result == nil || symbol.EndLine-symbol.Line < result.symbol.Symbol.EndLine-result.symbol.Symbol.Line {
+			result = node
+		}
+	}
+	return result
+}
+
+// walkFocus marks the symbols reachable from the targets within the given depth. Symbols already
+// reached at a lower depth (or in an earlier walk at the same depth) keep their relation.
+func walkFocus(targets []*focusNode, maxDepth int, relation string, next func(*focusNode) []*focusNode) {
+	visited := make(map[*focusNode]struct{}, len(targets))
+	for _, node := range targets {
+		visited[node] = struct{}{}
+	}
+
+	current := targets
+	for depth := 1; depth <= maxDepth && len(current) > 0; depth++ {
+		reached := make([]*focusNode, 0)
+		for _, node := range current {
+			for _, other := range next(node) {
+				if _, ok := visited[other]; ok {
+					continue
+				}
+				visited[other] = struct{}{}
+				reached = append(reached, other)
+				if other.relation == "" || other.depth > depth {
+					other.relation = relation
+					other.depth = depth
+				}
+			}
+		}
+		current = reached
+	}
+}
+
+// focusSnippets slices the declarations of the focused symbols from a file. Declarations nested
+// within another focused declaration (eg. the members of an enum) are not repeated.
+func focusSnippets(path PathInfo, nodes []*focusNode) ([]*FocusSnippet, error) {
+	content, err := os.ReadFile(path.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file %q: %w", path.Path, err)
+	}
+
+	slices.SortStableFunc(nodes, func(a, b *focusNode) int {
+		if a.symbol.Symbol.Start != b.symbol.Symbol.Start {
+			return a.symbol.Symbol.Start - b.symbol.Symbol.Start
+		}
+		return b.symbol.Symbol.End - a.symbol.Symbol.End
+	})
+
+	snippets := make([]*FocusSnippet, 0, len(nodes))
+	end := 0
+	for _, node := range nodes {
+		symbol := node.symbol.Symbol
+		if symbol.End == 0 || symbol.End > len(content) || symbol.Start < end {
+			continue
+		}
+		end = symbol.End
+		snippets = append(snippets, &FocusSnippet{
+			Path:      path,
+			Symbol:    symbol,
+			Relation:  node.relation,
+			Depth:     node.depth,
+			StartLine: bytes.Count(content[:symbol.Start], []byte("\n")) + 1,
+			EndLine:   symbol.EndLine,
+			Content:   string(content[symbol.Start:symbol.End]),
+		})
+	}
+	return snippets, nil
+}
+
+// writeFocusSnippets creates a textual representation of the focused declarations,
+// in the same manner as the dump of whole files.
+func writeFocusSnippets(snippets []*FocusSnippet) string {
+	var sb strings.Builder
+	sb.WriteString("## Focused Declarations\n")
+	for _, snippet := range snippets {
+		location := fmt.Sprintf("%s:%d-%d", snippet.Path.RelativePath, snippet.StartLine, snippet.EndLine)
+		relation := snippet.Relation
+		if snippet.Depth > 0 {
+			relation = fmt.Sprintf("%s at depth %d", relation, snippet.Depth)
+		}
+		sb.WriteString(fmt.Sprintf("\n--- Start Declaration: %s (%s: %s, %s)\n%s\n--- End Declaration: %s\n",
+			location, strings.ToUpper(snippet.Symbol.Type), snippet.Symbol.Name, relation, snippet.Content, location))
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitSelection selects the files of a git repository to traverse, instead of every file of the directory
+type GitSelection struct {
+	Mode string // Empty when files are not selected from git
+	Ref  string // Reference to compare against, for GitChangedSince
+}
+
+const (
+	GitTracked      = "tracked"
+	GitStaged       = "staged"
+	GitModified     = "modified"
+	GitChangedSince = "changed-since"
+)
+
+// ParseGitSelection parses a selection mode, which for GitChangedSince is followed by its reference (eg.
+// "changed-since main" or "changed-since=main"). A missing reference is not an error, so that it may be given
+// separately.
+func ParseGitSelection(s string) (GitSelection, error) {
+	mode, ref, _ := strings.Cut(strings.TrimSpace(s), "=")
+	if fields := strings.Fields(mode); len(fields) == 2 && ref == "" {
+		mode, ref = fields[0], fields[1]
+	}
+
+	switch mode {
+	case GitTracked, GitStaged, GitModified:
+		if ref != "" {
+			return GitSelection{}, fmt.Errorf("git selection %q does not take a reference", mode)
+		}
+	case GitChangedSince:
+		if strings.HasPrefix(ref, "-") {
+			return GitSelection{}, fmt.Errorf("invalid git reference %q", ref)
+		}
+	default:
+		return GitSelection{}, fmt.Errorf("unknown git selection %q (expected %s, %s, %s, or %s <ref>)",
+			mode, GitTracked, GitStaged, GitModified, GitChangedSince)
+	}
+	return GitSelection{Mode: mode, Ref: ref}, nil
+}
+
+// String formats the selection as it would be given to '--git' (eg. "changed-since main").
+func (g GitSelection) String() string {
+	if g.Ref == "" {
+		return g.Mode
+	}
+	return g.Mode + " " + g.Ref
+}
+
+// gitSelectedFiles returns the absolute paths of the files within the directory which are selected from its
+// repository, along with the directories containing them. The repository is queried with the git binary, which
+// only reads the local index and objects. Deleted files are not selected.
+//
+//   - tracked: the files in the index.
+//   - staged: the files whose changes are staged, compared to HEAD.
+//   - modified: the files whose changes are not yet staged, compared to the index.
+//   - changed-since: the files changed since the merge base of the reference and HEAD, whether committed,
+//     staged, or not, along with the untracked files which are not ignored.
+func gitSelectedFiles(dir string, selection GitSelection) (map[string]bool, error) {
+	if _, gitDir := findRepository(dir); gitDir == "" {
+		return nil, fmt.Errorf("directory %q is not within a git repository", dir)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("selecting files from git requires the git binary: %w", err)
+	}
+
+	diff := []string{"diff", "--name-only", "-z", "--relative", "--no-renames", "--diff-filter=d"}
+	commands := make([][]string, 0, 2)
+	switch selection.Mode {
+	case GitTracked:
+		commands = append(commands, []string{"ls-files", "-z"})
+	case GitStaged:
+		commands = append(commands, append(diff, "--cached"))
+	case GitModified:
+		commands = append(commands, diff)
+	case GitChangedSince:
+		if selection.Ref == "" {
+			return nil, fmt.Errorf("git selection %q requires a reference", selection.Mode)
+		}
+		if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", selection.Ref+"^{commit}"); err != nil {
+			return nil, fmt.Errorf("unknown git reference %q", selection.Ref)
+		}
+		commands = append(commands,
+			append(diff, "--merge-base", selection.Ref),
+			[]string{"ls-files", "-z", "--others", "--exclude-standard"},
+		)
+	default:
+		return nil, fmt.Errorf("unknown git selection %q", selection.Mode)
+	}
+
+	selected := make(map[string]bool)
+	for _, args := range commands {
+		output, err := runGit(dir, args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, relPath := range strings.Split(string(output), "\x00") {
+			if relPath == "" {
+				continue
+			}
+			path := filepath.Join(dir, filepath.FromSlash(relPath))
+			// Files deleted from the working tree are still listed by 'ls-files'.
+			if _, err := os.Lstat(path); err != nil {
+				continue
+			}
+			for current := path; current != dir && !selected[current]; current = filepath.Dir(current) {
+				selected[current] = true
+			}
+		}
+	}
+	return selected, nil
+}
+
+// runGit runs a git command within the directory, returning its output. Optional locks are disabled so that
+// the index is not refreshed while reading it.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("running 'git %s': %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	} else if err != nil {
+		return nil, fmt.Errorf("running 'git %s': %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
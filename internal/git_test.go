@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitSelection(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    GitSelection
+		wantErr bool
+	}{
+		"tracked": {
+			input: "tracked",
+			want:  GitSelection{Mode: GitTracked},
+		},
+		"changed since with space": {
+			input: "changed-since main",
+			want:  GitSelection{Mode: GitChangedSince, Ref: "main"},
+		},
+		"changed since with equals": {
+			input: "changed-since=origin/main",
+			want:  GitSelection{Mode: GitChangedSince, Ref: "origin/main"},
+		},
+		"changed since without reference": {
+			input: "changed-since",
+			want:  GitSelection{Mode: GitChangedSince},
+		},
+		"reference for other mode": {
+			input:   "staged=main",
+			wantErr: true,
+		},
+		"option as reference": {
+			input:   "changed-since --output=x",
+			wantErr: true,
+		},
+		"unknown mode": {
+			input:   "untracked",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseGitSelection(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
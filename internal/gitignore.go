@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Broderick-Westrope/amalgo/pkg/filter"
+)
+
+// gitignoreName is the name of the files discovered when respecting gitignore files.
+const gitignoreName = ".gitignore"
+
+// gitignoreMatcher tracks the ignore rules of a git repository while it is being walked. Patterns are
+// evaluated in order of precedence from lowest to highest: the user's core.excludesFile, the
+// repository's info/exclude file, and then each .gitignore file from the root of the repository down
+// to the directory being matched. The patterns of each file are anchored to the directory containing it.
+type gitignoreMatcher struct {
+	root   string // Directory at the root of the repository (or of the walk, outside a repository)
+	filter *filter.Filter
+	loaded map[string]bool // Directories whose .gitignore files have been loaded
+}
+
+// newGitignoreMatcher loads the ignore rules which apply to the given directory, excluding those in
+// .gitignore files within the directory itself (see addDir).
+func newGitignoreMatcher(dir string) (*gitignoreMatcher, error) {
+	root, gitDir := findRepository(dir)
+	m := &gitignoreMatcher{root: root, filter: new(filter.Filter), loaded: make(map[string]bool)}
+
+	files := make([]string, 0, 2)
+	if gitDir != "" {
+		excludesFile, err := readExcludesFile(filepath.Join(gitDir, "config"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, excludesFile, filepath.Join(gitDir, "info", "exclude"))
+	}
+	for _, path := range files {
+		if err := m.addFile(path, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Collect the .gitignore files of the directories above the walked directory.
+	ancestors := make([]string, 0)
+	for current := dir; current != root; {
+		current = filepath.Dir(current)
+		ancestors = append(ancestors, current)
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if err := m.addDir(ancestors[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addDir loads the .gitignore file of a directory, if it has one. Its patterns take precedence over
+// those already loaded but only apply within the directory.
+func (m *gitignoreMatcher) addDir(dir string) error {
+	if m.loaded[dir] {
+		return nil
+	}
+	m.loaded[dir] = true
+
+	base, err := filepath.Rel(m.root, dir)
+	if err != nil {
+		return fmt.Errorf("getting relative path between %q and %q: %w", m.root, dir, err)
+	}
+	if base == "." {
+		base = ""
+	}
+	return m.addFile(filepath.Join(dir, gitignoreName), filepath.ToSlash(base))
+}
+
+func (m *gitignoreMatcher) addFile(path, base string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := filter.CompileGitignoreFile(path, base)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("compiling patterns from gitignore file %q: %w", path, err)
+	}
+	m.filter.MergeWithPrecedence(f)
+	return nil
+}
+
+// ignored returns whether the file or directory at the given path is ignored and the pattern ignoring
+// it, which is nil for the .git directory. The contents of ignored directories are not matched, as they
+// should not be walked.
+func (m *gitignoreMatcher) ignored(path string, isDir bool) (bool, *filter.Pattern, error) {
+	if isDir && filepath.Base(path) == ".git" {
+		return true, nil, nil
+	}
+	relPath, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return false, nil, fmt.Errorf("getting relative path between %q and %q: %w", m.root, path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+	if isDir {
+		ignored, pattern := m.filter.MatchesDirHow(relPath)
+		return ignored, pattern, nil
+	}
+	ignored, pattern := m.filter.MatchesPathHow(relPath)
+	return ignored, pattern, nil
+}
+
+// findRepository searches the given directory and its parents for a git repository, returning its
+// root and git directory. Outside a repository the given directory is used as the root.
+func findRepository(dir string) (string, string) {
+	for current := dir; ; current = filepath.Dir(current) {
+		dotGit := filepath.Join(current, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil && info.IsDir() {
+			return current, dotGit
+		} else if err == nil {
+			// Worktrees and submodules use a file which points to the git directory.
+			bs, err := os.ReadFile(dotGit)
+			if gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(bs)), "gitdir:"); err == nil && ok {
+				gitDir = strings.TrimSpace(gitDir)
+				if !filepath.IsAbs(gitDir) {
+					gitDir = filepath.Join(current, gitDir)
+				}
+				return current, gitDir
+			}
+		}
+		if current == filepath.Dir(current) {
+			return dir, ""
+		}
+	}
+}
+
+// readExcludesFile returns the path of the user's excludes file. The core.excludesFile setting is read
+// from the global git config files and the repository's config, with the last taking precedence, and
+// otherwise defaults to "git/ignore" within the XDG config directory.
+func readExcludesFile(repoConfig string) (string, error) {
+	home, _ := os.UserHomeDir()
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" && home != "" {
+		xdgConfig = filepath.Join(home, ".config")
+	}
+
+	configs := make([]string, 0, 3)
+	excludesFile := ""
+	if xdgConfig != "" {
+		configs = append(configs, filepath.Join(xdgConfig, "git", "config"))
+		excludesFile = filepath.Join(xdgConfig, "git", "ignore")
+	}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	configs = append(configs, repoConfig)
+
+	for _, path := range configs {
+		value, ok, err := readGitConfigValue(path, "core", "excludesfile")
+		if err != nil {
+			return "", fmt.Errorf("reading git config %q: %w", path, err)
+		}
+		if !ok {
+			continue
+		}
+		if rest, found := strings.CutPrefix(value, "~/"); found && home != "" {
+			value = filepath.Join(home, rest)
+		}
+		excludesFile = value
+	}
+	return excludesFile, nil
+}
+
+// readGitConfigValue reads the last value of a key within a section of a git config file. Section and
+// key names are case-insensitive. A missing file is treated as an empty config.
+func readGitConfigValue(path, section, key string) (string, bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	value, found := "", false
+	currentSection := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				continue
+			}
+			// Subsections (eg. [remote "origin"]) are not distinguished.
+			name, _, _ := strings.Cut(line[1:end], " ")
+			name, _, _ = strings.Cut(name, ".")
+			currentSection = strings.ToLower(strings.TrimSpace(name))
+			line = strings.TrimSpace(line[end+1:])
+			if line == "" {
+				continue
+			}
+		}
+
+		name, rest, ok := strings.Cut(line, "=")
+		if !ok || currentSection != section || !strings.EqualFold(strings.TrimSpace(name), key) {
+			continue
+		}
+		value, found = parseGitConfigValue(rest), true
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, err
+	}
+	return value, found, nil
+}
+
+// parseGitConfigValue removes quotes, escapes, and trailing comments from a git config value.
+func parseGitConfigValue(s string) string {
+	var sb strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(s[i])
+			}
+		case (c == '#' || c == ';') && !quoted:
+			return strings.TrimSpace(sb.String())
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
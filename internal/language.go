@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// languageExtensions maps file extensions to their language. Language names are those commonly used to
+// highlight code blocks (eg. in Markdown).
+var languageExtensions = map[string]string{
+	".go":         "go",
+	".py":         "python",
+	".pyi":        "python",
+	".pyw":        "python",
+	".js":         "javascript",
+	".mjs":        "javascript",
+	".cjs":        "javascript",
+	".jsx":        "jsx",
+	".ts":         "typescript",
+	".mts":        "typescript",
+	".cts":        "typescript",
+	".tsx":        "tsx",
+	".rs":         "rust",
+	".java":       "java",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".scala":      "scala",
+	".c":          "c",
+	".h":          "c",
+	".cc":         "cpp",
+	".cpp":        "cpp",
+	".cxx":        "cpp",
+	".hh":         "cpp",
+	".hpp":        "cpp",
+	".hxx":        "cpp",
+	".cs":         "csharp",
+	".fs":         "fsharp",
+	".swift":      "swift",
+	".m":          "objectivec",
+	".rb":         "ruby",
+	".php":        "php",
+	".pl":         "perl",
+	".pm":         "perl",
+	".lua":        "lua",
+	".r":          "r",
+	".dart":       "dart",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".erl":        "erlang",
+	".hrl":        "erlang",
+	".hs":         "haskell",
+	".ml":         "ocaml",
+	".mli":        "ocaml",
+	".clj":        "clojure",
+	".cljs":       "clojure",
+	".zig":        "zig",
+	".nim":        "nim",
+	".jl":         "julia",
+	".sh":         "shell",
+	".bash":       "shell",
+	".zsh":        "shell",
+	".fish":       "fish",
+	".ps1":        "powershell",
+	".bat":        "batch",
+	".cmd":        "batch",
+	".sql":        "sql",
+	".html":       "html",
+	".htm":        "html",
+	".css":        "css",
+	".scss":       "scss",
+	".sass":       "sass",
+	".less":       "less",
+	".vue":        "vue",
+	".svelte":     "svelte",
+	".json":       "json",
+	".jsonc":      "json",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".toml":       "toml",
+	".xml":        "xml",
+	".ini":        "ini",
+	".md":         "markdown",
+	".rst":        "rst",
+	".tex":        "latex",
+	".proto":      "protobuf",
+	".graphql":    "graphql",
+	".gql":        "graphql",
+	".tf":         "terraform",
+	".hcl":        "hcl",
+	".nix":        "nix",
+	".vim":        "vim",
+	".mk":         "make",
+	".cmake":      "cmake",
+	".dockerfile": "dockerfile",
+	".txt":        "text",
+}
+
+// languageFilenames maps well-known file names, which have no extension or a misleading one, to their language.
+var languageFilenames = map[string]string{
+	"Makefile":       "make",
+	"makefile":       "make",
+	"GNUmakefile":    "make",
+	"Dockerfile":     "dockerfile",
+	"Containerfile":  "dockerfile",
+	"CMakeLists.txt": "cmake",
+	"Rakefile":       "ruby",
+	"Gemfile":        "ruby",
+	"Vagrantfile":    "ruby",
+	"Jenkinsfile":    "groovy",
+	"BUILD":          "starlark",
+	"BUILD.bazel":    "starlark",
+	"WORKSPACE":      "starlark",
+	"go.mod":         "gomod",
+	"go.work":        "gomod",
+	".bashrc":        "shell",
+	".bash_profile":  "shell",
+	".zshrc":         "shell",
+	".profile":       "shell",
+}
+
+// languageInterpreters maps the interpreters of shebang lines, without their version, to their language.
+var languageInterpreters = map[string]string{
+	"python":  "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"bun":     "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"sh":      "shell",
+	"bash":    "shell",
+	"dash":    "shell",
+	"ksh":     "shell",
+	"zsh":     "shell",
+	"fish":    "fish",
+	"pwsh":    "powershell",
+	"Rscript": "r",
+	"make":    "make",
+}
+
+// languageAliases maps alternative names of languages, as used by modelines and '--lang', to their language.
+var languageAliases = map[string]string{
+	"golang":     "go",
+	"py":         "python",
+	"python3":    "python",
+	"js":         "javascript",
+	"ts":         "typescript",
+	"rs":         "rust",
+	"c++":        "cpp",
+	"cs":         "csharp",
+	"c#":         "csharp",
+	"rb":         "ruby",
+	"sh":         "shell",
+	"bash":       "shell",
+	"zsh":        "shell",
+	"yml":        "yaml",
+	"md":         "markdown",
+	"makefile":   "make",
+	"docker":     "dockerfile",
+	"objc":       "objectivec",
+	"tf":         "terraform",
+	"emacs-lisp": "elisp",
+	"plaintext":  "text",
+}
+
+var (
+	// vimModeline matches vim modelines (eg. "vim: set ft=python:" or "vi: filetype=sh").
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:.*?\b(?:ft|filetype|syntax)=([\w+#-]+)`)
+	// emacsModeline matches emacs modelines (eg. "-*- mode: python -*-" or "-*- python -*-").
+	emacsModeline = regexp.MustCompile(`-\*-(?:.*?\bmode:\s*([\w+#-]+)|\s*([\w+#-]+)\s*)(?:;.*?)?-\*-`)
+)
+
+const (
+	// languageSampleSize is the number of bytes read from the start and end of a file to detect its language.
+	languageSampleSize = 1024
+	// modelineLines is the number of lines at the start and end of a file which are searched for modelines.
+	modelineLines = 5
+)
+
+// NormalizeLanguage returns the language with the given name or alias, and whether it is known.
+func NormalizeLanguage(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := languageAliases[name]; ok {
+		return alias, true
+	}
+	for _, languages := range []map[string]string{languageExtensions, languageFilenames, languageInterpreters} {
+		for _, language := range languages {
+			if language == name {
+				return language, true
+			}
+		}
+	}
+	return name, false
+}
+
+// DetectLanguage returns the language of a file, or an empty string if it is unknown. As with GitHub's
+// linguist, a modeline takes precedence over the name of the file, which takes precedence over the
+// interpreter of a shebang line, which takes precedence over the extension.
+func DetectLanguage(path string) (string, error) {
+	head, tail, err := readLanguageSample(path)
+	if err != nil {
+		return "", err
+	}
+	// Binary files only have their name and extension checked.
+	binary := bytes.IndexByte(head, 0) >= 0
+
+	if !binary {
+		if language := detectModeline(head, tail); language != "" {
+			return language, nil
+		}
+	}
+	name := filepath.Base(path)
+	if language, ok := languageFilenames[name]; ok {
+		return language, nil
+	}
+	if !binary {
+		if language := detectShebang(head); language != "" {
+			return language, nil
+		}
+	}
+	if language, ok := languageExtensions[strings.ToLower(filepath.Ext(name))]; ok {
+		return language, nil
+	}
+	// Names such as "Dockerfile.dev" are recognized by their prefix.
+	if prefix, _, found := strings.Cut(name, "."); found && prefix != "" {
+		if language, ok := languageFilenames[prefix]; ok {
+			return language, nil
+		}
+	}
+	return "", nil
+}
+
+// readLanguageSample reads the start and the end of a file. The end is empty when the file fits in the start.
+func readLanguageSample(path string) ([]byte, []byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, languageSampleSize)
+	n, err := io.ReadFull(file, head)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return head[:n], nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	offset := max(info.Size()-languageSampleSize, languageSampleSize)
+	tail := make([]byte, languageSampleSize)
+	n, err = file.ReadAt(tail, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+	return head, tail[:n], nil
+}
+
+// detectModeline returns the language of a vim or emacs modeline within the first or last lines of a file.
+func detectModeline(head, tail []byte) string {
+	lines := strings.Split(string(head), "\n")
+	candidates := lines[:min(len(lines), modelineLines)]
+	if tail == nil {
+		tail = head
+	}
+	lines = strings.Split(strings.TrimRight(string(tail), "\n"), "\n")
+	candidates = append(candidates, lines[max(len(lines)-modelineLines, 0):]...)
+
+	for _, line := range candidates {
+		var name string
+		if match := vimModeline.FindStringSubmatch(line); match != nil {
+			name = match[1]
+		} else if match := emacsModeline.FindStringSubmatch(line); match != nil {
+			name = match[1] + match[2]
+		} else {
+			continue
+		}
+		language, _ := NormalizeLanguage(strings.TrimSuffix(name, "-mode"))
+		return language
+	}
+	return ""
+}
+
+// detectShebang returns the language of the interpreter of a shebang line (eg. "#!/usr/bin/env python3").
+func detectShebang(head []byte) string {
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	rest, ok := strings.CutPrefix(strings.TrimSpace(string(line)), "#!")
+	if !ok {
+		return ""
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Options of env (eg. "-S") are skipped, along with variable assignments.
+		i := slices.IndexFunc(fields[1:], func(field string) bool {
+			return !strings.HasPrefix(field, "-") && !strings.Contains(field, "=")
+		})
+		if i < 0 {
+			return ""
+		}
+		interpreter = fields[i+1]
+	}
+
+	// Versions are removed from the interpreter (eg. "python3.12").
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	return languageInterpreters[interpreter]
+}
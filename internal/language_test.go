@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		content string
+		want    string
+	}{
+		"extension":           {name: "main.go", content: "package main\n", want: "go"},
+		"uppercase extension": {name: "README.MD", content: "# Title\n", want: "markdown"},
+		"unknown extension":   {name: "data.xyz", content: "data\n", want: ""},
+		"no extension":        {name: "LICENSE", content: "MIT\n", want: ""},
+		"well-known name":     {name: "Makefile", content: "all:\n\tgo build\n", want: "make"},
+		"well-known prefix":   {name: "Dockerfile.dev", content: "FROM scratch\n", want: "dockerfile"},
+		"name before extension": {
+			name:    "CMakeLists.txt",
+			content: "project(x)\n",
+			want:    "cmake",
+		},
+		"shebang": {
+			name:    "run",
+			content: "#!/bin/bash\necho hi\n",
+			want:    "shell",
+		},
+		"shebang with env": {
+			name:    "tool",
+			content: "#!/usr/bin/env python3\nprint('hi')\n",
+			want:    "python",
+		},
+		"shebang with env options": {
+			name:    "tool",
+			content: "#!/usr/bin/env -S node --no-warnings\nconsole.log(1)\n",
+			want:    "javascript",
+		},
+		"shebang with versioned interpreter": {
+			name:    "tool",
+			content: "#!/usr/local/bin/python3.12\n",
+			want:    "python",
+		},
+		"shebang before extension": {
+			name:    "script.txt",
+			content: "#!/bin/sh\necho hi\n",
+			want:    "shell",
+		},
+		"vim modeline": {
+			name:    "config",
+			content: "# vim: set ft=python :\nx = 1\n",
+			want:    "python",
+		},
+		"vim modeline with alias": {
+			name:    "notes.txt",
+			content: "notes\n\n# vim: ft=sh\n",
+			want:    "shell",
+		},
+		"emacs modeline": {
+			name:    "build",
+			content: "# -*- mode: ruby; coding: utf-8 -*-\n",
+			want:    "ruby",
+		},
+		"short emacs modeline": {
+			name:    "build",
+			content: "# -*- perl -*-\n",
+			want:    "perl",
+		},
+		"emacs coding only": {
+			name:    "script.py",
+			content: "# -*- coding: utf-8 -*-\n",
+			want:    "python",
+		},
+		"modeline at end of large file": {
+			name:    "Makefile.inc",
+			content: strings.Repeat("x = 1\n", 1000) + "# vim: ft=make\n",
+			want:    "make",
+		},
+		"modeline in middle of large file": {
+			name:    "notes",
+			content: strings.Repeat("x = 1\n", 500) + "# vim: ft=make\n" + strings.Repeat("x = 1\n", 500),
+			want:    "",
+		},
+		"binary": {
+			name:    "image.png",
+			content: "\x89PNG\x00\x00 vim: ft=go",
+			want:    "",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			got, err := DetectLanguage(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := map[string]struct {
+		name      string
+		want      string
+		wantKnown bool
+	}{
+		"name":    {name: "go", want: "go", wantKnown: true},
+		"alias":   {name: "py", want: "python", wantKnown: true},
+		"case":    {name: "Python", want: "python", wantKnown: true},
+		"unknown": {name: "klingon", want: "klingon", wantKnown: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, known := NormalizeLanguage(tt.name)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantKnown, known)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteSize is a number of bytes, which may be parsed with a unit (eg. "200KB" or "1MiB")
+type ByteSize int64
+
+// byteSizeUnits maps the units of sizes to their number of bytes. As with most tools, the SI units
+// (eg. KB) are powers of 1000 while the binary units (eg. KiB) are powers of 1024.
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+}
+
+var byteSizePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]*)$`)
+
+// ParseByteSize parses a size with an optional unit (eg. "512", "200KB", "1.5MB" or "2MiB").
+func ParseByteSize(s string) (ByteSize, error) {
+	match := byteSizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	multiplier, ok := byteSizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, match[2])
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	return ByteSize(value * float64(multiplier)), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// String formats the size using the largest SI unit which keeps the value above one (eg. "1.2 MB").
+func (b ByteSize) String() string {
+	switch {
+	case b >= 1e9:
+		return fmt.Sprintf("%.1f GB", float64(b)/1e9)
+	case b >= 1e6:
+		return fmt.Sprintf("%.1f MB", float64(b)/1e6)
+	case b >= 1e3:
+		return fmt.Sprintf("%.1f KB", float64(b)/1e3)
+	}
+	return fmt.Sprintf("%d B", int64(b))
+}
+
+// Since is a point in time, which may be parsed as a date or a duration before now (eg. "2026-09-01" or "7d")
+type Since struct {
+	time.Time
+}
+
+var sinceDurationPattern = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)([a-zµ]+)`)
+
+// ParseSince parses a date (eg. "2026-09-01"), a time in RFC 3339 format, or a duration before now. Durations
+// are those accepted by time.ParseDuration, along with days and weeks (eg. "7d" or "1w2d").
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	// Days and weeks are converted to hours, which time.ParseDuration supports.
+	matches := sinceDurationPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 || matches[0][0] != 0 || matches[len(matches)-1][1] != len(s) {
+		return time.Time{}, fmt.Errorf("invalid date or duration %q", s)
+	}
+	var total time.Duration
+	for i, match := range matches {
+		if i > 0 && matches[i-1][1] != match[0] {
+			return time.Time{}, fmt.Errorf("invalid date or duration %q", s)
+		}
+		number, unit := s[match[2]:match[3]], s[match[4]:match[5]]
+		hours := map[string]float64{"d": 24, "w": 24 * 7}[unit]
+		if hours > 0 {
+			value, _ := strconv.ParseFloat(number, 64)
+			total += time.Duration(value * hours * float64(time.Hour))
+			continue
+		}
+		d, err := time.ParseDuration(number + unit)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date or duration %q", s)
+		}
+		total += d
+	}
+	return now.Add(-total), nil
+}
+
+// String formats the time in the local time zone, or returns an empty string if it is unset.
+func (s Since) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return s.Local().Format(time.DateTime)
+}
+
+func (s *Since) UnmarshalText(text []byte) error {
+	t, err := ParseSince(string(text), time.Now())
+	if err != nil {
+		return err
+	}
+	s.Time = t
+	return nil
+}
+
+// checkMetadata returns the reason a file should be skipped based on its size, modification time, and number of
+// lines, or an empty string if it should not be skipped.
+func checkMetadata(path string, d fs.DirEntry, opts TraverseOptions) (string, error) {
+	if opts.MaxSize <= 0 && opts.MinSize <= 0 && opts.ModifiedSince.IsZero() && opts.MaxLines <= 0 {
+		return "", nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return "", fmt.Errorf("describing file %q: %w", path, err)
+	}
+	size := ByteSize(info.Size())
+	switch {
+	case opts.MaxSize > 0 && size > opts.MaxSize:
+		return fmt.Sprintf("size of %s exceeds the maximum of %s", size, opts.MaxSize), nil
+	case opts.MinSize > 0 && size < opts.MinSize:
+		return fmt.Sprintf("size of %s is below the minimum of %s", size, opts.MinSize), nil
+	case !opts.ModifiedSince.IsZero() && info.ModTime().Before(opts.ModifiedSince):
+		return fmt.Sprintf("last modified %s, before %s",
+			info.ModTime().Format(time.DateTime), opts.ModifiedSince.Format(time.DateTime)), nil
+	}
+
+	if opts.MaxLines > 0 {
+		lines, err := countLines(path, opts.MaxLines+1)
+		if err != nil {
+			return "", fmt.Errorf("counting lines of file %q: %w", path, err)
+		}
+		if lines > opts.MaxLines {
+			return fmt.Sprintf("more than the maximum of %d lines", opts.MaxLines), nil
+		}
+	}
+	return "", nil
+}
+
+// countLines counts the lines of a file, stopping once the limit is reached. A final line without a trailing
+// newline is counted. The file is read in chunks so that large files are not loaded into memory.
+func countLines(path string, limit int) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 32*1024)
+	lines := 0
+	var last byte = '\n'
+	for lines < limit {
+		n, err := file.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte("\n"))
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    ByteSize
+		wantErr bool
+	}{
+		"bytes without unit": {input: "512", want: 512},
+		"bytes with unit":    {input: "10B", want: 10},
+		"kilobytes":          {input: "200KB", want: 200_000},
+		"short lowercase":    {input: "200k", want: 200_000},
+		"fractional":         {input: "1.5MB", want: 1_500_000},
+		"kibibytes":          {input: "2KiB", want: 2048},
+		"mebibytes":          {input: "1 MiB", want: 1 << 20},
+		"gigabytes":          {input: "1GB", want: 1_000_000_000},
+		"unknown unit":       {input: "2XB", wantErr: true},
+		"negative":           {input: "-1KB", wantErr: true},
+		"missing number":     {input: "KB", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		"date": {
+			input: "2026-09-01",
+			want:  time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local),
+		},
+		"RFC 3339": {
+			input: "2026-09-01T10:30:00Z",
+			want:  time.Date(2026, 9, 1, 10, 30, 0, 0, time.UTC),
+		},
+		"days": {
+			input: "7d",
+			want:  now.Add(-7 * 24 * time.Hour),
+		},
+		"weeks and days": {
+			input: "1w2d",
+			want:  now.Add(-9 * 24 * time.Hour),
+		},
+		"days and hours": {
+			input: "1d12h",
+			want:  now.Add(-36 * time.Hour),
+		},
+		"minutes": {
+			input: "90m",
+			want:  now.Add(-90 * time.Minute),
+		},
+		"unknown unit": {
+			input:   "7x",
+			wantErr: true,
+		},
+		"missing unit": {
+			input:   "7",
+			wantErr: true,
+		},
+		"trailing characters": {
+			input:   "7d ago",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseSince(tt.input, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		limit   int
+		want    int
+	}{
+		"empty":                    {content: "", limit: 10, want: 0},
+		"trailing newline":         {content: "a\nb\n", limit: 10, want: 2},
+		"without trailing newline": {content: "a\nb", limit: 10, want: 2},
+		"stops at limit":           {content: "a\nb\nc\nd\n", limit: 2, want: 2},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			got, err := countLines(path, tt.limit)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, got, min(tt.want, tt.limit))
+			if tt.want < tt.limit {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strings"
 
@@ -17,17 +19,36 @@ const (
 
 // Options configures the output generation
 type OutputOptions struct {
-	NoTree     bool
-	NoDump     bool
-	Outline    bool
-	SkipBinary bool
-	Format     OutputFormat
+	NoTree            bool
+	NoDump            bool
+	Outline           bool
+	References        bool
+	Dependencies      bool
+	DependencyDiagram DependencyDiagram
+	Focus             []string // Symbols to focus on, limiting the output to their declarations and files
+	FocusDepth        int      // Number of references to follow from the focused symbols
+	Declarations      []string // Dump only the matching declarations of code files ("exported" or name globs)
+	SkipBinary        bool
+	Summarize         bool // Summarize generated, vendored, and lock files instead of dumping their contents
+	Format            OutputFormat
+	Warnings          *Warnings // Collects the files which cannot be read, unless nil
 }
 
-// GenerateOutput creates the complete output string
-func GenerateOutput(paths []PathInfo, registry *parser.Registry, opts OutputOptions) (string, error) {
+// GenerateOutput creates the complete output string. Skipped files are listed at the end, along with
+// those which could not be read.
+func GenerateOutput(paths []PathInfo, skipped []SkippedPath, registry *parser.Registry, opts OutputOptions) (string, error) {
+	var focus *Focus
+	if len(opts.Focus) > 0 {
+		var err error
+		focus, err = GenerateFocus(paths, registry, opts.Focus, opts.FocusDepth, opts.Warnings)
+		if err != nil {
+			return "", fmt.Errorf("generating focus: %w", err)
+		}
+		paths = focus.Paths
+	}
+
 	if opts.Format == OutputFormatJSON {
-		return generateOutputJSON(paths, registry, opts)
+		return generateOutputJSON(paths, skipped, registry, focus, opts)
 	}
 
 	output := fmt.Sprintf("## Generated with Amalgo at: %s\n\n", FormatTimestamp())
@@ -36,31 +57,80 @@ func GenerateOutput(paths []PathInfo, registry *parser.Registry, opts OutputOpti
 		output += fmt.Sprintf("## File Tree\n\n%s\n", GenerateTree(paths))
 	}
 
-	if opts.Outline {
-		outlines, err := generateOutlines(paths, registry)
+	if opts.Dependencies {
+		graph, err := GenerateDependencyGraph(paths, opts.Warnings)
+		if err != nil {
+			return "", fmt.Errorf("generating dependency graph: %w", err)
+		}
+		output += fmt.Sprintf("## Dependency Graph\n\n%s\n", writeDependencyGraph(graph))
+		if opts.DependencyDiagram != DependencyDiagramNone && opts.DependencyDiagram != "" {
+			output += fmt.Sprintf("```%s\n%s```\n\n", opts.DependencyDiagram, writeDependencyDiagram(graph, opts.DependencyDiagram))
+		}
+	}
+
+	// Outlines are always included for the files containing the focused declarations.
+	if opts.Outline || focus != nil {
+		outlines, err := generateOutlines(paths, registry, opts.Warnings)
 		if err != nil {
 			return "", fmt.Errorf("generating outlines: %w", err)
 		}
 		output += outlines
 	}
 
-	if !opts.NoDump {
-		filesDump, err := dumpFiles(paths, opts.SkipBinary)
+	if opts.References {
+		refs, err := generateReferences(paths, registry, focus, opts.Warnings)
+		if err != nil {
+			return "", fmt.Errorf("generating references: %w", err)
+		}
+		output += fmt.Sprintf("## References\n\n%s", writeReferences(refs))
+	}
+
+	if !opts.NoDump && focus != nil {
+		output += writeFocusSnippets(focus.Snippets)
+	} else if !opts.NoDump {
+		filesDump, err := dumpFiles(paths, registry, opts)
 		if err != nil {
 			return "", fmt.Errorf("dumping files: %w", err)
 		}
 		output += filesDump
 	}
+
+	if warnings := opts.Warnings.List(); len(skipped) > 0 || len(warnings) > 0 {
+		if !strings.HasSuffix(output, "\n\n") {
+			output += "\n"
+		}
+		output += writeSkippedFiles(skipped, warnings)
+	}
 	return output, nil
 }
 
-func generateOutlines(paths []PathInfo, registry *parser.Registry) (string, error) {
+// generateReferences returns the references of the selected files, reusing those resolved for the focus if any.
+func generateReferences(paths []PathInfo, registry *parser.Registry, focus *Focus, warnings *Warnings) ([]*FileReferences, error) {
+	if focus != nil {
+		return focus.References, nil
+	}
+	return GenerateReferences(paths, registry, warnings)
+}
+
+func writeSkippedFiles(skipped []SkippedPath, warnings []Warning) string {
+	var sb strings.Builder
+	sb.WriteString("## Skipped Files\n\n")
+	for _, s := range skipped {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", s.RelativePath, s.Reason))
+	}
+	for _, w := range warnings {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", w.RelativePath, w.Message()))
+	}
+	return sb.String()
+}
+
+func generateOutlines(paths []PathInfo, registry *parser.Registry, warnings *Warnings) (string, error) {
 	output := "## Language-Specific Outlines\n\n"
 
 	var temp string
 	var err error
 	for _, path := range paths {
-		if path.IsDir {
+		if path.IsDir || path.Link != "" {
 			continue
 		}
 
@@ -69,43 +139,51 @@ func generateOutlines(paths []PathInfo, registry *parser.Registry) (string, erro
 			continue
 		}
 
-		temp, err = processFileOutline(path.Path, registry)
+		temp, err = processFileOutline(path, registry)
 		if err != nil {
-			return "", fmt.Errorf("processing outline for %q: %w", path.Path, err)
+			if err := warnings.Add(path.Path, path.RelativePath, err); err != nil {
+				return "", fmt.Errorf("processing outline for %q: %w", path.Path, err)
+			}
+			continue
 		}
 		output += fmt.Sprintf("### File: %s\n\n%s\n", path.RelativePath, temp)
 	}
 	return output, nil
 }
 
-func processFileOutline(filePath string, registry *parser.Registry) (string, error) {
-	content, err := os.ReadFile(filePath)
+func processFileOutline(path PathInfo, registry *parser.Registry) (string, error) {
+	content, err := os.ReadFile(path.Path)
 	if err != nil {
 		return "", fmt.Errorf("reading file: %w", err)
 	}
 
-	parser := registry.GetParser(filePath)
-	if parser == nil {
-		return "", fmt.Errorf("no parser found for %q", filePath)
+	p := registry.GetParser(path.Path)
+	if p == nil {
+		return "", fmt.Errorf("no parser found for %q", path.Path)
 	}
 
-	outline, err := parser.Parse(content, filePath)
+	outline, err := p.Parse(content, path.Path)
 	if err != nil {
-		return "", fmt.Errorf("parsing file %q: %w", filePath, err)
-	}
-
-	if len(outline.Errors) > 0 {
-		var errMsgs []string
-		for _, err := range outline.Errors {
-			errMsgs = append(errMsgs, err.Error())
-		}
-		return fmt.Sprintf("Parsing errors:\n%s\n", strings.Join(errMsgs, "\n")), nil
+		return "", fmt.Errorf("parsing file %q: %w", path.Path, err)
 	}
 
 	result, err := writeSymbols(outline.Symbols, 0)
 	if err != nil {
 		return "", fmt.Errorf("writing symbols: %w", err)
 	}
+
+	// Symbols recovered from a partially parsed file are kept, followed by the errors.
+	if len(outline.Errors) > 0 {
+		result += "Parsing errors:\n"
+		for _, err := range outline.Errors {
+			var parseErr *parser.ParseError
+			if errors.As(err, &parseErr) {
+				result += fmt.Sprintf("  %s:%d:%d: %s\n", path.RelativePath, parseErr.Line, parseErr.Column, parseErr.Message)
+				continue
+			}
+			result += fmt.Sprintf("  %s\n", err.Error())
+		}
+	}
 	return result, nil
 }
 
@@ -134,6 +212,19 @@ func writeSymbols(symbols []*parser.Symbol, depth int) (string, error) {
 			}
 		}
 
+		// Write function summary if present
+		if summary, ok := symbol.Metadata["summary"].(*parser.FuncSummary); ok {
+			output += writeFuncSummary(summary, indent+"  ")
+		}
+
+		// Write the declaration and output of examples
+		if documents, ok := symbol.Metadata["documents"].(string); ok {
+			output += fmt.Sprintf("%s  Documents: %s\n", indent, documents)
+		}
+		if exampleOutput, ok := symbol.Metadata["output"].(string); ok {
+			output += writeExampleOutput(exampleOutput, symbol.Metadata["unordered"] == true, indent+"  ")
+		}
+
 		// Recursively write children
 		if len(symbol.Children) > 0 {
 			temp, err := writeSymbols(symbol.Children, depth+1)
@@ -146,20 +237,79 @@ func writeSymbols(symbols []*parser.Symbol, depth int) (string, error) {
 	return output, nil
 }
 
-func dumpFiles(paths []PathInfo, skipBinary bool) (string, error) {
+// writeExampleOutput writes the expected output of an example, on its own line when it is a single line
+// (eg. "Output: 3").
+func writeExampleOutput(exampleOutput string, unordered bool, indent string) string {
+	label := "Output"
+	if unordered {
+		label = "Unordered output"
+	}
+	lines := strings.Split(exampleOutput, "\n")
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s%s: %s\n", indent, label, exampleOutput)
+	}
+	output := fmt.Sprintf("%s%s:\n", indent, label)
+	for _, line := range lines {
+		output += fmt.Sprintf("%s  %s\n", indent, line)
+	}
+	return output
+}
+
+func writeFuncSummary(summary *parser.FuncSummary, indent string) string {
+	details := []string{fmt.Sprintf("complexity %d", summary.Complexity)}
+	if summary.Lines == 1 {
+		details = append(details, "1 line")
+	} else {
+		details = append(details, fmt.Sprintf("%d lines", summary.Lines))
+	}
+	if summary.Goroutines {
+		details = append(details, "spawns goroutines")
+	}
+	if summary.Defer {
+		details = append(details, "uses defer")
+	}
+	if summary.Recover {
+		details = append(details, "uses recover")
+	}
+
+	output := fmt.Sprintf("%sSummary: %s\n", indent, strings.Join(details, ", "))
+	if len(summary.Calls) > 0 {
+		output += fmt.Sprintf("%sCalls: %s\n", indent, strings.Join(summary.Calls, ", "))
+	}
+	return output
+}
+
+func dumpFiles(paths []PathInfo, registry *parser.Registry, opts OutputOptions) (string, error) {
 	var sb strings.Builder
 	sb.WriteString("## File Contents\n")
 
 	for _, path := range paths {
-		if path.IsDir {
+		// Recorded symbolic links are only listed in the tree.
+		if path.IsDir || path.Link != "" {
+			continue
+		}
+
+		if opts.Summarize && path.Class != "" {
+			summary, err := summarizeFile(path)
+			if err != nil {
+				if err := opts.Warnings.Add(path.Path, path.RelativePath, err); err != nil {
+					return "", err
+				}
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("\n--- File: %s\n<%s>\n", path.RelativePath, summary))
 			continue
 		}
 
-		if skipBinary {
+		if opts.SkipBinary {
 			// Check if file is binary
 			isBinary, err := IsBinaryFile(path.Path)
 			if err != nil {
-				return "", fmt.Errorf("checking if file %q is binary: %w", path.Path, err)
+				err = fmt.Errorf("checking if file %q is binary: %w", path.Path, err)
+				if err := opts.Warnings.Add(path.Path, path.RelativePath, err); err != nil {
+					return "", err
+				}
+				continue
 			}
 
 			if isBinary {
@@ -171,15 +321,64 @@ func dumpFiles(paths []PathInfo, skipBinary bool) (string, error) {
 		}
 
 		// Read and write file content
-		fileContent, err := os.ReadFile(path.Path)
+		fileContent, _, err := readDumpContent(path, registry, opts.Declarations)
 		if err != nil {
-			return "", fmt.Errorf("reading file %q: %w", path.Path, err)
+			if err := opts.Warnings.Add(path.Path, path.RelativePath, err); err != nil {
+				return "", err
+			}
+			continue
 		}
 
 		sb.WriteString(
 			fmt.Sprintf("\n--- Start File: %s\n%s\n--- End File: %s\n",
-				path.RelativePath, string(fileContent), path.RelativePath),
+				path.RelativePath, fileContent, path.RelativePath),
 		)
 	}
 	return sb.String(), nil
 }
+
+// summarizeFile describes a classified file in place of its contents (eg. "generated: 1.2 KB, 40 lines").
+func summarizeFile(path PathInfo) (string, error) {
+	size, lines, err := measureFile(path.Path)
+	if err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("%s: %s, %d lines", path.Class, size, lines)
+	if lines == 1 {
+		summary = fmt.Sprintf("%s: %s, 1 line", path.Class, size)
+	}
+	return summary, nil
+}
+
+// measureFile returns the size and number of lines of a file.
+func measureFile(path string) (ByteSize, int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("describing file %q: %w", path, err)
+	}
+	lines, err := countLines(path, math.MaxInt)
+	if err != nil {
+		return 0, 0, fmt.Errorf("counting lines of file %q: %w", path, err)
+	}
+	return ByteSize(info.Size()), lines, nil
+}
+
+// readDumpContent reads the content of a file to be dumped. When declarations are selected and
+// a parser is available, only the header of the file and the matching declarations are kept,
+// and the number of omitted lines is returned.
+func readDumpContent(path PathInfo, registry *parser.Registry, declarations []string) (string, int, error) {
+	content, err := os.ReadFile(path.Path)
+	if err != nil {
+		return "", 0, fmt.Errorf("reading file %q: %w", path.Path, err)
+	}
+	if len(declarations) == 0 || !registry.IsSupported(path.Path) {
+		return string(content), 0, nil
+	}
+
+	outline, err := registry.GetParser(path.Path).Parse(content, path.Path)
+	if err != nil {
+		return "", 0, fmt.Errorf("parsing file %q: %w", path.Path, err)
+	}
+	sliced, omitted := sliceDeclarations(content, outline, declarations)
+	return sliced, omitted, nil
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -9,23 +10,103 @@ import (
 )
 
 type JSONDocument struct {
-	Timestamp string            `json:"timestamp"`
-	Tree      string            `json:"tree,omitempty"`
-	Files     []JSONFile        `json:"files,omitempty"`
-	Outlines  []JSONFileOutline `json:"outlines,omitempty"`
+	Timestamp    string               `json:"timestamp"`
+	Tree         string               `json:"tree,omitempty"`
+	Dependencies *JSONDependencyGraph `json:"dependencies,omitempty"`
+	Files        []JSONFile           `json:"files,omitempty"`
+	Outlines     []JSONFileOutline    `json:"outlines,omitempty"`
+	References   []JSONFileReferences `json:"references,omitempty"`
+	Snippets     []JSONSnippet        `json:"snippets,omitempty"`
+	Skipped      []JSONSkippedFile    `json:"skipped,omitempty"`
+	Warnings     []JSONWarning        `json:"warnings,omitempty"`
 }
 
-type JSONFile struct {
+// JSONSkippedFile represents a file which was selected by the filters but skipped
+type JSONSkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// JSONWarning represents a path which could not be read, and was skipped
+type JSONWarning struct {
 	Path    string `json:"path"`
-	Content string `json:"content,omitempty"`
-	Binary  bool   `json:"binary,omitempty"`
+	Message string `json:"message"`
+}
+
+// JSONSnippet represents the exact source of the declaration of a focused symbol
+type JSONSnippet struct {
+	Path      string `json:"path"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Relation  string `json:"relation"`        // "target", "callee" or "caller"
+	Depth     int    `json:"depth,omitempty"` // Number of references followed from the nearest target
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Content   string `json:"content"`
+}
+
+// JSONFileReferences lists where the symbols declared in a file are referenced
+type JSONFileReferences struct {
+	Path    string                 `json:"path"`
+	Symbols []JSONSymbolReferences `json:"symbols"`
+}
+
+// JSONSymbolReferences lists where a symbol is referenced within the selected files
+type JSONSymbolReferences struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Line       int             `json:"line,omitempty"` // Line on which the symbol is declared
+	References []JSONReference `json:"references"`
+}
+
+// JSONReference represents the position of a reference to a symbol
+type JSONReference struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// JSONDependencyGraph represents the imports between the Go packages of the selected files
+type JSONDependencyGraph struct {
+	Packages []JSONPackage `json:"packages"`
+	Cycles   [][]string    `json:"cycles,omitempty"`         // Packages which import each other, directly or indirectly
+	FanIn    []string      `json:"highest_fan_in,omitempty"` // Packages imported by the most other packages
+	Diagram  string        `json:"diagram,omitempty"`        // DOT or Mermaid representation of the graph
+}
+
+// JSONPackage represents a Go package and its imports from the same module
+type JSONPackage struct {
+	ImportPath string   `json:"import_path"`
+	Name       string   `json:"name,omitempty"`
+	Imports    []string `json:"imports,omitempty"`
+	ImportedBy []string `json:"imported_by,omitempty"`
+}
+
+type JSONFile struct {
+	Path         string `json:"path"`
+	Language     string `json:"language,omitempty"`
+	Content      string `json:"content,omitempty"`
+	Binary       bool   `json:"binary,omitempty"`
+	OmittedLines int    `json:"omitted_lines,omitempty"` // Lines removed when dumping only the selected declarations
+	Class        string `json:"class,omitempty"`         // "generated", "vendored" or "lockfile"
+	Summarized   bool   `json:"summarized,omitempty"`    // Whether the content was omitted in favour of the size and lines
+	Size         int64  `json:"size,omitempty"`          // Size in bytes of a summarized file
+	Lines        int    `json:"lines,omitempty"`         // Number of lines of a summarized file
+	Link         string `json:"link,omitempty"`          // Target of a recorded symbolic link, whose content is omitted
 }
 
 // JSONFileOutline represents the parsed structure of a source file
 type JSONFileOutline struct {
-	Path    string       `json:"path"`
-	Symbols []JSONSymbol `json:"symbols,omitempty"`
-	Errors  []string     `json:"errors,omitempty"`
+	Path    string           `json:"path"`
+	Symbols []JSONSymbol     `json:"symbols,omitempty"`
+	Errors  []JSONParseError `json:"errors,omitempty"`
+}
+
+// JSONParseError represents a problem encountered while parsing a source file
+type JSONParseError struct {
+	Line    int    `json:"line,omitempty"`   // Line number, starting at 1
+	Column  int    `json:"column,omitempty"` // Column number in bytes, starting at 1
+	Message string `json:"message"`          // Description of the problem
 }
 
 // JSONSymbol represents a parsed symbol (function, type, class, etc.)
@@ -39,7 +120,7 @@ type JSONSymbol struct {
 	Metadata      any          `json:"metadata,omitempty"`      // Additional language-specific metadata
 }
 
-func generateOutputJSON(paths []PathInfo, registry *parser.Registry, opts OutputOptions) (string, error) {
+func generateOutputJSON(paths []PathInfo, skipped []SkippedPath, registry *parser.Registry, focus *Focus, opts OutputOptions) (string, error) {
 	doc := JSONDocument{
 		Timestamp: FormatTimestamp(),
 	}
@@ -48,22 +129,47 @@ func generateOutputJSON(paths []PathInfo, registry *parser.Registry, opts Output
 		doc.Tree = GenerateTree(paths)
 	}
 
-	if !opts.NoDump {
-		files, err := generateFilesJSON(paths, opts.SkipBinary)
+	if opts.Dependencies {
+		graph, err := GenerateDependencyGraph(paths, opts.Warnings)
+		if err != nil {
+			return "", fmt.Errorf("generating dependency graph: %w", err)
+		}
+		doc.Dependencies = convertDependencyGraph(graph, opts.DependencyDiagram)
+	}
+
+	if !opts.NoDump && focus != nil {
+		doc.Snippets = convertSnippets(focus.Snippets)
+	} else if !opts.NoDump {
+		files, err := generateFilesJSON(paths, registry, opts)
 		if err != nil {
 			return "", fmt.Errorf("dumping files: %w", err)
 		}
 		doc.Files = files
 	}
 
-	if opts.Outline {
-		outlines, err := generateOutlinesJSON(paths, registry)
+	if opts.Outline || focus != nil {
+		outlines, err := generateOutlinesJSON(paths, registry, opts.Warnings)
 		if err != nil {
 			return "", fmt.Errorf("generating outlines: %w", err)
 		}
 		doc.Outlines = outlines
 	}
 
+	if opts.References {
+		refs, err := generateReferences(paths, registry, focus, opts.Warnings)
+		if err != nil {
+			return "", fmt.Errorf("generating references: %w", err)
+		}
+		doc.References = convertReferences(refs)
+	}
+
+	for _, s := range skipped {
+		doc.Skipped = append(doc.Skipped, JSONSkippedFile{Path: s.RelativePath, Reason: s.Reason})
+	}
+	for _, w := range opts.Warnings.List() {
+		doc.Warnings = append(doc.Warnings, JSONWarning{Path: w.RelativePath, Message: w.Message()})
+	}
+
 	output, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("marshaling JSON: %w", err)
@@ -72,7 +178,7 @@ func generateOutputJSON(paths []PathInfo, registry *parser.Registry, opts Output
 	return string(output) + "\n", nil
 }
 
-func generateFilesJSON(paths []PathInfo, skipBinary bool) ([]JSONFile, error) {
+func generateFilesJSON(paths []PathInfo, registry *parser.Registry, opts OutputOptions) ([]JSONFile, error) {
 	files := make([]JSONFile, 0, len(paths))
 
 	for _, path := range paths {
@@ -80,50 +186,89 @@ func generateFilesJSON(paths []PathInfo, skipBinary bool) ([]JSONFile, error) {
 			continue
 		}
 
-		if skipBinary {
+		if path.Link != "" {
+			files = append(files, JSONFile{Path: path.RelativePath, Link: path.Link})
+			continue
+		}
+
+		if opts.Summarize && path.Class != "" {
+			size, lines, err := measureFile(path.Path)
+			if err != nil {
+				if err := opts.Warnings.Add(path.Path, path.RelativePath, err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			files = append(files, JSONFile{
+				Path:       path.RelativePath,
+				Language:   path.Language,
+				Class:      string(path.Class),
+				Summarized: true,
+				Size:       int64(size),
+				Lines:      lines,
+			})
+			continue
+		}
+
+		if opts.SkipBinary {
 			isBinary, err := IsBinaryFile(path.Path)
 			if err != nil {
-				return nil, fmt.Errorf("checking if binary: %w", err)
+				err = fmt.Errorf("checking if binary: %w", err)
+				if err := opts.Warnings.Add(path.Path, path.RelativePath, err); err != nil {
+					return nil, err
+				}
+				continue
 			}
 
 			if isBinary {
 				files = append(files, JSONFile{
-					Path:   path.RelativePath,
-					Binary: true,
+					Path:     path.RelativePath,
+					Language: path.Language,
+					Binary:   true,
+					Class:    string(path.Class),
 				})
 				continue
 			}
 		}
 
-		content, err := os.ReadFile(path.Path)
+		content, omitted, err := readDumpContent(path, registry, opts.Declarations)
 		if err != nil {
-			return nil, fmt.Errorf("reading file %s: %w", path.Path, err)
+			if err := opts.Warnings.Add(path.Path, path.RelativePath, err); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		files = append(files, JSONFile{
-			Path:    path.RelativePath,
-			Content: string(content),
+			Path:         path.RelativePath,
+			Language:     path.Language,
+			Content:      content,
+			OmittedLines: omitted,
+			Class:        string(path.Class),
 		})
 	}
 
 	return files, nil
 }
 
-func generateOutlinesJSON(paths []PathInfo, registry *parser.Registry) ([]JSONFileOutline, error) {
+func generateOutlinesJSON(paths []PathInfo, registry *parser.Registry, warnings *Warnings) ([]JSONFileOutline, error) {
 	outlines := make([]JSONFileOutline, 0)
 
 	for _, path := range paths {
-		if path.IsDir || !registry.IsSupported(path.Path) {
+		if path.IsDir || path.Link != "" || !registry.IsSupported(path.Path) {
 			continue
 		}
 
 		content, err := os.ReadFile(path.Path)
 		if err != nil {
-			return nil, fmt.Errorf("reading file %s: %w", path.Path, err)
+			if err := warnings.Add(path.Path, path.RelativePath, err); err != nil {
+				return nil, fmt.Errorf("reading file %s: %w", path.Path, err)
+			}
+			continue
 		}
 
-		parser := registry.GetParser(path.Path)
-		parsedOutline, err := parser.Parse(content, path.Path)
+		p := registry.GetParser(path.Path)
+		parsedOutline, err := p.Parse(content, path.Path)
 		if err != nil {
 			return nil, fmt.Errorf("parsing file %s: %w", path.Path, err)
 		}
@@ -144,9 +289,18 @@ func generateOutlinesJSON(paths []PathInfo, registry *parser.Registry) ([]JSONFi
 
 		// Add any parsing errors
 		if len(parsedOutline.Errors) > 0 {
-			outline.Errors = make([]string, len(parsedOutline.Errors))
+			outline.Errors = make([]JSONParseError, len(parsedOutline.Errors))
 			for i, err := range parsedOutline.Errors {
-				outline.Errors[i] = err.Error()
+				var parseErr *parser.ParseError
+				if errors.As(err, &parseErr) {
+					outline.Errors[i] = JSONParseError{
+						Line:    parseErr.Line,
+						Column:  parseErr.Column,
+						Message: parseErr.Message,
+					}
+					continue
+				}
+				outline.Errors[i] = JSONParseError{Message: err.Error()}
 			}
 		}
 
@@ -176,3 +330,69 @@ func convertSymbol(ps *parser.Symbol) (JSONSymbol, error) {
 		Metadata:      ps.Metadata,
 	}, nil
 }
+
+func convertDependencyGraph(graph *DependencyGraph, diagram DependencyDiagram) *JSONDependencyGraph {
+	result := &JSONDependencyGraph{
+		Packages: make([]JSONPackage, 0, len(graph.Packages)),
+		Cycles:   graph.Cycles,
+	}
+	for _, node := range graph.Packages {
+		result.Packages = append(result.Packages, JSONPackage{
+			ImportPath: node.ImportPath,
+			Name:       node.Name,
+			Imports:    node.Imports,
+			ImportedBy: node.ImportedBy,
+		})
+	}
+	for _, node := range graph.HighestFanIn() {
+		result.FanIn = append(result.FanIn, node.ImportPath)
+	}
+	if diagram != DependencyDiagramNone && diagram != "" {
+		result.Diagram = writeDependencyDiagram(graph, diagram)
+	}
+	return result
+}
+
+func convertReferences(files []*FileReferences) []JSONFileReferences {
+	result := make([]JSONFileReferences, 0, len(files))
+	for _, file := range files {
+		if len(file.Symbols) == 0 {
+			continue
+		}
+		jsonFile := JSONFileReferences{
+			Path:    file.Path.RelativePath,
+			Symbols: make([]JSONSymbolReferences, 0, len(file.Symbols)),
+		}
+		for _, symbolRefs := range file.Symbols {
+			refs := make([]JSONReference, 0, len(symbolRefs.References))
+			for _, ref := range symbolRefs.References {
+				refs = append(refs, JSONReference(ref))
+			}
+			jsonFile.Symbols = append(jsonFile.Symbols, JSONSymbolReferences{
+				Type:       symbolRefs.Symbol.Type,
+				Name:       symbolRefs.Symbol.Name,
+				Line:       symbolRefs.Symbol.Line,
+				References: refs,
+			})
+		}
+		result = append(result, jsonFile)
+	}
+	return result
+}
+
+func convertSnippets(snippets []*FocusSnippet) []JSONSnippet {
+	result := make([]JSONSnippet, 0, len(snippets))
+	for _, snippet := range snippets {
+		result = append(result, JSONSnippet{
+			Path:      snippet.Path.RelativePath,
+			Type:      snippet.Symbol.Type,
+			Name:      snippet.Symbol.Name,
+			Relation:  snippet.Relation,
+			Depth:     snippet.Depth,
+			StartLine: snippet.StartLine,
+			EndLine:   snippet.EndLine,
+			Content:   snippet.Content,
+		})
+	}
+	return result
+}
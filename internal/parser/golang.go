@@ -1,18 +1,29 @@
 package parser
 
 import (
+	"errors"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
+	"strconv"
 	"strings"
 )
 
 // GoParser implements Parser for Go source files
-type GoParser struct{}
+type GoParser struct {
+	opts GoParserOptions
+}
+
+// GoParserOptions configures the Go parser
+type GoParserOptions struct {
+	// Summaries adds a structural summary of the body of each function and method.
+	Summaries bool
+}
 
 // NewGoParser creates a new Go parser
-func NewGoParser() *GoParser {
-	return &GoParser{}
+func NewGoParser(opts GoParserOptions) *GoParser {
+	return &GoParser{opts: opts}
 }
 
 func (p *GoParser) Extensions() []string {
@@ -22,28 +33,71 @@ func (p *GoParser) Extensions() []string {
 func (p *GoParser) Parse(content []byte, filename string) (*FileOutline, error) {
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
-	if err != nil {
-		return &FileOutline{
-			Filename: filename,
-			Errors:   []error{err},
-		}, nil
-	}
 
 	outline := &FileOutline{
 		Filename: filename,
 		Symbols:  make([]*Symbol, 0),
 	}
 
+	// Syntax errors still produce a partial AST, so the recoverable declarations are outlined.
+	if err != nil {
+		var errList scanner.ErrorList
+		if !errors.As(err, &errList) {
+			outline.Errors = append(outline.Errors, err)
+		}
+		for _, e := range errList {
+			outline.Errors = append(outline.Errors, &ParseError{
+				Filename: e.Pos.Filename,
+				Line:     e.Pos.Line,
+				Column:   e.Pos.Column,
+				Message:  e.Msg,
+			})
+		}
+	}
+	if file == nil {
+		return outline, nil
+	}
+	outline.Package = file.Name.Name
+	header := file.Name.End()
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			header = genDecl.End()
+		}
+	}
+	outline.Header = fset.Position(header).Offset
+
 	// Process package-level declarations
+	isTestFile := strings.HasSuffix(filename, "_test.go")
+	scope := newConstScope(file)
 	for _, decl := range file.Decls {
-		symbols := p.processDecl(decl, file)
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			var symbol *Symbol
+			if isTestFile {
+				symbol = p.processTestFunc(funcDecl, fset, file)
+			}
+			if symbol == nil {
+				symbol = p.processDecl(decl, fset, file)[0]
+			}
+			setRange(symbol, fset, funcDecl, funcDecl.Doc)
+			if p.opts.Summaries {
+				setMetadata(symbol, "summary", p.summarizeFunc(funcDecl, fset, file))
+			}
+			outline.Symbols = append(outline.Symbols, symbol)
+			continue
+		}
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.CONST {
+			outline.Symbols = append(outline.Symbols, p.processConstDecl(genDecl, fset, scope)...)
+			continue
+		}
+		symbols := p.processDecl(decl, fset, file)
 		outline.Symbols = append(outline.Symbols, symbols...)
 	}
+	outline.Symbols = p.groupEnums(outline.Symbols)
 
 	return outline, nil
 }
 
-func (p *GoParser) processDecl(decl ast.Decl, file *ast.File) []*Symbol {
+func (p *GoParser) processDecl(decl ast.Decl, fset *token.FileSet, file *ast.File) []*Symbol {
 	var symbols []*Symbol
 
 	switch d := decl.(type) {
@@ -53,6 +107,7 @@ func (p *GoParser) processDecl(decl ast.Decl, file *ast.File) []*Symbol {
 			Name:      d.Name.Name,
 			Signature: p.getFunctionSignature(d),
 			Docstring: p.getDocstring(d.Doc),
+			Line:      fset.Position(d.Name.Pos()).Line,
 		}
 
 		// Handle methods
@@ -74,17 +129,19 @@ func (p *GoParser) processDecl(decl ast.Decl, file *ast.File) []*Symbol {
 					symbol := &Symbol{
 						Type:      p.getTypeSymbolType(typeSpec),
 						Name:      typeSpec.Name.Name,
-						Docstring: p.getDocstring(d.Doc),
+						Docstring: p.getSpecDocstring(typeSpec.Doc, d.Doc),
+						Line:      fset.Position(typeSpec.Name.Pos()).Line,
 					}
+					setSpecRange(symbol, fset, d, typeSpec, typeSpec.Doc)
 
 					// Handle interface methods and struct fields
 					if symbol.Type == "interface" {
 						if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok {
-							symbol.Children = p.processInterface(iface)
+							symbol.Children = p.processInterface(iface, fset)
 						}
 					} else if symbol.Type == "struct" {
 						if structType, ok := typeSpec.Type.(*ast.StructType); ok {
-							symbol.Children = p.processStruct(structType)
+							symbol.Children = p.processStruct(structType, fset)
 						}
 					}
 
@@ -92,15 +149,17 @@ func (p *GoParser) processDecl(decl ast.Decl, file *ast.File) []*Symbol {
 				}
 			}
 
-		case token.CONST, token.VAR:
+		case token.VAR:
 			for _, spec := range d.Specs {
 				if valSpec, ok := spec.(*ast.ValueSpec); ok {
 					for _, name := range valSpec.Names {
 						symbol := &Symbol{
-							Type:      strings.ToLower(d.Tok.String()),
+							Type:      "var",
 							Name:      name.Name,
-							Docstring: p.getDocstring(d.Doc),
+							Docstring: p.getSpecDocstring(valSpec.Doc, d.Doc),
+							Line:      fset.Position(name.Pos()).Line,
 						}
+						setSpecRange(symbol, fset, d, valSpec, valSpec.Doc)
 						if valSpec.Type != nil {
 							symbol.Signature = p.typeToString(valSpec.Type)
 						}
@@ -114,7 +173,30 @@ func (p *GoParser) processDecl(decl ast.Decl, file *ast.File) []*Symbol {
 	return symbols
 }
 
-func (p *GoParser) processInterface(iface *ast.InterfaceType) []*Symbol {
+// setRange records the lines and byte offsets spanned by the declaration of the symbol,
+// including its documentation.
+func setRange(symbol *Symbol, fset *token.FileSet, node ast.Node, doc *ast.CommentGroup) {
+	start := node.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	end := fset.Position(node.End())
+	symbol.Start = fset.Position(start).Offset
+	symbol.End = end.Offset
+	symbol.EndLine = end.Line
+}
+
+// setSpecRange records the range of a symbol declared by a spec. A spec which is not within a
+// parenthesised group spans the whole declaration (eg. `type T int` rather than `T int`).
+func setSpecRange(symbol *Symbol, fset *token.FileSet, d *ast.GenDecl, spec ast.Spec, doc *ast.CommentGroup) {
+	if !d.Lparen.IsValid() {
+		setRange(symbol, fset, d, d.Doc)
+		return
+	}
+	setRange(symbol, fset, spec, doc)
+}
+
+func (p *GoParser) processInterface(iface *ast.InterfaceType, fset *token.FileSet) []*Symbol {
 	var methods []*Symbol
 	if iface.Methods == nil {
 		return methods
@@ -122,7 +204,19 @@ func (p *GoParser) processInterface(iface *ast.InterfaceType) []*Symbol {
 
 	for _, method := range iface.Methods.List {
 		if len(method.Names) == 0 {
-			continue // Skip embedded interfaces
+			// Embedded interfaces (eg. io.Reader) and type set elements (eg. ~int | ~string).
+			symbol := &Symbol{
+				Type:      "embedded",
+				Name:      p.typeToString(method.Type),
+				Docstring: p.getFieldDocstring(method),
+				Line:      fset.Position(method.Type.Pos()).Line,
+			}
+			switch method.Type.(type) {
+			case *ast.UnaryExpr, *ast.BinaryExpr:
+				symbol.Type = "constraint"
+			}
+			methods = append(methods, symbol)
+			continue
 		}
 
 		methodType, ok := method.Type.(*ast.FuncType)
@@ -135,7 +229,8 @@ func (p *GoParser) processInterface(iface *ast.InterfaceType) []*Symbol {
 				Type:      "method",
 				Name:      name.Name,
 				Signature: p.getFuncTypeSignature(methodType),
-				Docstring: p.getDocstring(method.Doc),
+				Docstring: p.getFieldDocstring(method),
+				Line:      fset.Position(name.Pos()).Line,
 			}
 			methods = append(methods, symbol)
 		}
@@ -144,20 +239,31 @@ func (p *GoParser) processInterface(iface *ast.InterfaceType) []*Symbol {
 	return methods
 }
 
-func (p *GoParser) processStruct(structType *ast.StructType) []*Symbol {
+func (p *GoParser) processStruct(structType *ast.StructType, fset *token.FileSet) []*Symbol {
 	var fields []*Symbol
 	if structType.Fields == nil {
 		return fields
 	}
 
 	for _, field := range structType.Fields.List {
+		signature := p.typeToString(field.Type)
+		var metadata map[string]any
+		if field.Tag != nil {
+			signature += " " + field.Tag.Value
+			if tags := parseStructTag(field.Tag.Value); len(tags) > 0 {
+				metadata = map[string]any{"tags": tags}
+			}
+		}
+
 		if len(field.Names) == 0 {
 			// Anonymous/embedded field
 			symbol := &Symbol{
 				Type:      "field",
 				Name:      p.typeToString(field.Type),
-				Signature: p.typeToString(field.Type),
-				Docstring: p.getDocstring(field.Doc),
+				Signature: signature,
+				Docstring: p.getFieldDocstring(field),
+				Metadata:  metadata,
+				Line:      fset.Position(field.Type.Pos()).Line,
 			}
 			fields = append(fields, symbol)
 			continue
@@ -167,8 +273,10 @@ func (p *GoParser) processStruct(structType *ast.StructType) []*Symbol {
 			symbol := &Symbol{
 				Type:      "field",
 				Name:      name.Name,
-				Signature: p.typeToString(field.Type),
-				Docstring: p.getDocstring(field.Doc),
+				Signature: signature,
+				Docstring: p.getFieldDocstring(field),
+				Metadata:  metadata,
+				Line:      fset.Position(name.Pos()).Line,
 			}
 			fields = append(fields, symbol)
 		}
@@ -278,6 +386,20 @@ func (p *GoParser) typeToString(expr ast.Expr) string {
 		return "struct{...}"
 	case *ast.Ellipsis:
 		return "..." + p.typeToString(t.Elt)
+	case *ast.IndexExpr:
+		return p.typeToString(t.X) + "[" + p.typeToString(t.Index) + "]"
+	case *ast.IndexListExpr:
+		indices := make([]string, 0, len(t.Indices))
+		for _, index := range t.Indices {
+			indices = append(indices, p.typeToString(index))
+		}
+		return p.typeToString(t.X) + "[" + strings.Join(indices, ", ") + "]"
+	case *ast.UnaryExpr:
+		return t.Op.String() + p.typeToString(t.X)
+	case *ast.BinaryExpr:
+		return p.typeToString(t.X) + " " + t.Op.String() + " " + p.typeToString(t.Y)
+	case *ast.ParenExpr:
+		return "(" + p.typeToString(t.X) + ")"
 	default:
 		return "<unknown>"
 	}
@@ -300,3 +422,69 @@ func (p *GoParser) getDocstring(doc *ast.CommentGroup) string {
 	}
 	return doc.Text()
 }
+
+// getFieldDocstring combines the documentation above a field with its trailing line comment.
+func (p *GoParser) getFieldDocstring(field *ast.Field) string {
+	doc := p.getDocstring(field.Doc)
+	if field.Comment != nil {
+		doc += p.getDocstring(field.Comment)
+	}
+	return doc
+}
+
+// getSpecDocstring prefers the documentation attached to a single spec, falling back
+// to the documentation of the enclosing declaration (eg. for unparenthesised declarations).
+func (p *GoParser) getSpecDocstring(specDoc, declDoc *ast.CommentGroup) string {
+	if specDoc != nil {
+		return p.getDocstring(specDoc)
+	}
+	return p.getDocstring(declDoc)
+}
+
+// parseStructTag parses a struct tag literal (eg. `json:"name,omitempty" db:"name"`) into its
+// key/value pairs using the conventional format described by reflect.StructTag.
+func parseStructTag(literal string) map[string]string {
+	tag, err := strconv.Unquote(literal)
+	if err != nil {
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for tag != "" {
+		// Skip leading space.
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tags[key] = value
+		tag = tag[i+1:]
+	}
+	return tags
+}
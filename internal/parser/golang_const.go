@@ -0,0 +1,348 @@
+package parser
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// predeclaredTypes are the builtin Go types. Constants of these types are never grouped as enums.
+var predeclaredTypes = map[string]struct{}{
+	"bool": {}, "byte": {}, "complex64": {}, "complex128": {}, "error": {}, "float32": {}, "float64": {},
+	"int": {}, "int8": {}, "int16": {}, "int32": {}, "int64": {}, "rune": {}, "string": {},
+	"uint": {}, "uint8": {}, "uint16": {}, "uint32": {}, "uint64": {}, "uintptr": {}, "any": {},
+}
+
+// basicType is the underlying type of a typed constant, which decides how its operations are evaluated.
+type basicType struct {
+	kind constant.Kind // Bool, String, Int, Float or Complex
+	size uint          // Size in bits of an unsigned integer type, or 0 for other types
+}
+
+// basicTypes are the predeclared types which constants may have.
+var basicTypes = map[string]basicType{
+	"bool": {kind: constant.Bool}, "string": {kind: constant.String},
+	"int": {kind: constant.Int}, "int8": {kind: constant.Int}, "int16": {kind: constant.Int},
+	"int32": {kind: constant.Int}, "int64": {kind: constant.Int}, "rune": {kind: constant.Int},
+	"uint": {kind: constant.Int, size: 64}, "uint8": {kind: constant.Int, size: 8}, "byte": {kind: constant.Int, size: 8},
+	"uint16": {kind: constant.Int, size: 16}, "uint32": {kind: constant.Int, size: 32},
+	"uint64": {kind: constant.Int, size: 64}, "uintptr": {kind: constant.Int, size: 64},
+	"float32": {kind: constant.Float}, "float64": {kind: constant.Float},
+	"complex64": {kind: constant.Complex}, "complex128": {kind: constant.Complex},
+}
+
+// typedConst is the value of a constant expression, along with its type if it is typed.
+type typedConst struct {
+	value constant.Value
+	typ   *basicType // Nil for untyped constants
+}
+
+func unknownConst() typedConst {
+	return typedConst{value: constant.MakeUnknown()}
+}
+
+// constScope holds what constant expressions of a file may refer to: the constants resolved so far,
+// and the types declared in the file.
+type constScope struct {
+	values map[string]typedConst
+	types  map[string]ast.Expr // Types declared in the file to the types they are defined as
+}
+
+func newConstScope(file *ast.File) *constScope {
+	scope := &constScope{values: make(map[string]typedConst), types: make(map[string]ast.Expr)}
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			scope.types[typeSpec.Name.Name] = typeSpec.Type
+		}
+	}
+	return scope
+}
+
+// underlying returns the basic type underlying a type expression (eg. uint8 for `type Mask uint8`). It returns
+// false if the type is not a basic type, or is declared elsewhere.
+func (s *constScope) underlying(expr ast.Expr) (*basicType, bool) {
+	// Types defined as each other are not followed indefinitely.
+	for range len(s.types) + 1 {
+		if paren, ok := expr.(*ast.ParenExpr); ok {
+			expr = paren.X
+			continue
+		}
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return nil, false
+		}
+		if typ, ok := s.types[ident.Name]; ok {
+			expr = typ
+			continue
+		}
+		basic, ok := basicTypes[ident.Name]
+		return &basic, ok
+	}
+	return nil, false
+}
+
+// convertConst converts a constant to a basic type, as an explicit conversion or the assignment of an untyped
+// constant would. The value is unknown if it cannot be represented by the type.
+func convertConst(c typedConst, typ *basicType) typedConst {
+	value := c.value
+	switch {
+	case typ.kind == constant.String && value.Kind() == constant.Int:
+		// Integers are converted to the UTF-8 encoding of the character (eg. string(rune(65)) is "A").
+		r, ok := constant.Int64Val(value)
+		if !ok {
+			return unknownConst()
+		}
+		value = constant.MakeString(string(rune(r)))
+	case typ.kind == constant.Int:
+		value = constant.ToInt(value)
+		if typ.size > 0 && value.Kind() == constant.Int && constant.Sign(value) < 0 {
+			return unknownConst()
+		}
+	case typ.kind == constant.Float:
+		value = constant.ToFloat(value)
+	case typ.kind == constant.Complex:
+		value = constant.ToComplex(value)
+	}
+	if value.Kind() != typ.kind {
+		return unknownConst()
+	}
+	return typedConst{value: value, typ: typ}
+}
+
+// processConstDecl converts a const declaration into symbols. Constants declared in a parenthesised
+// group with a named type from this package (eg. `const ( A Kind = iota; B; C )`) are collected as
+// members of an "enum" symbol named after that type. Resolved values are recorded in the scope so
+// that later declarations may refer to them.
+func (p *GoParser) processConstDecl(d *ast.GenDecl, fset *token.FileSet, scope *constScope) []*Symbol {
+	var symbols []*Symbol
+	enums := make(map[string]*Symbol)
+
+	// Specs without a type or values repeat the previous type and expressions (with a new iota).
+	var typ ast.Expr
+	var values []ast.Expr
+	for specIndex, spec := range d.Specs {
+		valSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if valSpec.Type != nil || len(valSpec.Values) > 0 {
+			typ, values = valSpec.Type, valSpec.Values
+		}
+
+		enumType := ""
+		if ident, ok := typ.(*ast.Ident); ok && d.Lparen.IsValid() {
+			if _, ok := predeclaredTypes[ident.Name]; !ok {
+				enumType = ident.Name
+			}
+		}
+
+		for i, name := range valSpec.Names {
+			c := unknownConst()
+			if i < len(values) {
+				c = evalConstExpr(values[i], int64(specIndex), scope)
+			}
+			// Constants of types declared elsewhere keep the value of their expression.
+			if basic, ok := scope.underlying(typ); ok && c.value.Kind() != constant.Unknown {
+				c = convertConst(c, basic)
+			}
+			value := c.value
+			if name.Name != "_" && value.Kind() != constant.Unknown {
+				scope.values[name.Name] = c
+			}
+
+			if enumType == "" {
+				symbol := &Symbol{
+					Type:      "const",
+					Name:      name.Name,
+					Docstring: p.getSpecDocstring(valSpec.Doc, d.Doc),
+					Line:      fset.Position(name.Pos()).Line,
+				}
+				setSpecRange(symbol, fset, d, valSpec, valSpec.Doc)
+				if valSpec.Type != nil {
+					symbol.Signature = p.typeToString(valSpec.Type)
+				}
+				symbols = append(symbols, symbol)
+				continue
+			}
+
+			if name.Name == "_" {
+				continue
+			}
+			enum, ok := enums[enumType]
+			if !ok {
+				enum = &Symbol{
+					Type:      "enum",
+					Name:      enumType,
+					Docstring: p.getDocstring(d.Doc),
+					Line:      fset.Position(name.Pos()).Line,
+				}
+				setRange(enum, fset, d, d.Doc)
+				enums[enumType] = enum
+				symbols = append(symbols, enum)
+			}
+
+			member := &Symbol{
+				Type:      "enum_member",
+				Name:      name.Name,
+				Signature: enumType,
+				Docstring: p.getSpecDocstring(valSpec.Doc, valSpec.Comment),
+				Line:      fset.Position(name.Pos()).Line,
+			}
+			// The members of an enum are only meaningful within their group.
+			setRange(member, fset, d, d.Doc)
+			if value.Kind() != constant.Unknown {
+				member.Signature += " = " + constValueString(value)
+			}
+			enum.Children = append(enum.Children, member)
+		}
+	}
+	return symbols
+}
+
+// groupEnums moves the members of each enum symbol under the declaration of its named type
+// when that type is declared in the same file. Enums for types declared elsewhere are kept
+// as standalone symbols, with repeated groups for the same type merged into the first.
+func (p *GoParser) groupEnums(symbols []*Symbol) []*Symbol {
+	types := make(map[string]*Symbol)
+	for _, symbol := range symbols {
+		if symbol.Type == "type" {
+			types[symbol.Name] = symbol
+		}
+	}
+
+	result := make([]*Symbol, 0, len(symbols))
+	for _, symbol := range symbols {
+		if symbol.Type != "enum" {
+			result = append(result, symbol)
+			continue
+		}
+
+		target, ok := types[symbol.Name]
+		if !ok {
+			types[symbol.Name] = symbol
+			result = append(result, symbol)
+			continue
+		}
+		if target.Type == "type" {
+			target.Type = "enum"
+		}
+		if target.Docstring == "" {
+			target.Docstring = symbol.Docstring
+		}
+		target.Children = append(target.Children, symbol.Children...)
+	}
+	return result
+}
+
+// evalConstExpr evaluates a constant expression in the same manner as the Go type checker, using
+// the basic types underlying the types declared in the file. The value is unknown if the expression
+// cannot be resolved (eg. it refers to constants or types from other files or packages).
+func evalConstExpr(expr ast.Expr, iotaValue int64, scope *constScope) (c typedConst) {
+	// The constant package panics on invalid operations (eg. adding a string to an int).
+	defer func() {
+		if recover() != nil {
+			c = unknownConst()
+		}
+	}()
+
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return typedConst{value: constant.MakeFromLiteral(e.Value, e.Kind, 0)}
+
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return typedConst{value: constant.MakeInt64(iotaValue)}
+		case "true", "false":
+			return typedConst{value: constant.MakeBool(e.Name == "true")}
+		}
+		if c, ok := scope.values[e.Name]; ok {
+			return c
+		}
+
+	case *ast.ParenExpr:
+		return evalConstExpr(e.X, iotaValue, scope)
+
+	case *ast.CallExpr:
+		// Conversions such as Kind(iota) or uint8(1 << iota) convert the value of their operand, while
+		// other calls (eg. len("abc")) and conversions to types declared elsewhere are unknown.
+		typ, ok := scope.underlying(e.Fun)
+		if len(e.Args) != 1 || !ok {
+			return unknownConst()
+		}
+		x := evalConstExpr(e.Args[0], iotaValue, scope)
+		if x.value.Kind() == constant.Unknown {
+			return x
+		}
+		return convertConst(x, typ)
+
+	case *ast.UnaryExpr:
+		x := evalConstExpr(e.X, iotaValue, scope)
+		if x.value.Kind() == constant.Unknown {
+			return x
+		}
+		// The complement of an unsigned integer is within its size (eg. ^uint8(0) is 255).
+		var prec uint
+		if x.typ != nil {
+			prec = x.typ.size
+		}
+		return typedConst{value: constant.UnaryOp(e.Op, x.value, prec), typ: x.typ}
+
+	case *ast.BinaryExpr:
+		x := evalConstExpr(e.X, iotaValue, scope)
+		y := evalConstExpr(e.Y, iotaValue, scope)
+		if x.value.Kind() == constant.Unknown || y.value.Kind() == constant.Unknown {
+			return unknownConst()
+		}
+
+		switch e.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(constant.ToInt(y.value))
+			if !ok {
+				return unknownConst()
+			}
+			return typedConst{value: constant.Shift(x.value, e.Op, uint(s)), typ: x.typ}
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return typedConst{value: constant.MakeBool(constant.Compare(x.value, e.Op, y.value))}
+		}
+
+		// Untyped operands take the type of the other operand.
+		typ := x.typ
+		if typ == nil {
+			typ = y.typ
+		}
+		if typ != nil {
+			x, y = convertConst(x, typ), convertConst(y, typ)
+			if x.value.Kind() == constant.Unknown || y.value.Kind() == constant.Unknown {
+				return unknownConst()
+			}
+		}
+		op := e.Op
+		if op == token.QUO || op == token.REM {
+			if constant.Sign(y.value) == 0 {
+				return unknownConst()
+			}
+			if op == token.QUO && x.value.Kind() == constant.Int && y.value.Kind() == constant.Int {
+				// Force integer division, matching the semantics of integer constants.
+				op = token.QUO_ASSIGN
+			}
+		}
+		return typedConst{value: constant.BinaryOp(x.value, op, y.value), typ: typ}
+	}
+	return unknownConst()
+}
+
+// constValueString formats a constant value the way it would appear in Go source.
+func constValueString(value constant.Value) string {
+	switch value.Kind() {
+	case constant.Float, constant.Complex:
+		return value.String()
+	default:
+		return value.ExactString()
+	}
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// FindReferences returns the uses of package-level symbols within a Go file.
+// Identifiers are resolved without type information: unqualified identifiers which do not refer to
+// a local declaration belong to the package of the file, qualified identifiers (eg. fmt.Println)
+// belong to the imported package, and methods are resolved when the type of the receiver is known
+// (eg. receivers, parameters and explicitly typed variables).
+func (p *GoParser) FindReferences(content []byte, filename string) ([]Reference, error) {
+	fset := token.NewFileSet()
+	// Syntax errors are reported by Parse. The references within the partial AST are still returned.
+	file, _ := parser.ParseFile(fset, filename, content, 0)
+	if file == nil {
+		return nil, nil
+	}
+
+	unresolved := make(map[*ast.Ident]bool, len(file.Unresolved))
+	for _, ident := range file.Unresolved {
+		unresolved[ident] = true
+	}
+	// isPackageLevel reports whether the identifier refers to a package-level declaration,
+	// excluding the identifiers which declare them.
+	isPackageLevel := func(ident *ast.Ident) bool {
+		if unresolved[ident] {
+			return true
+		}
+		return ident.Obj != nil && file.Scope.Lookup(ident.Name) == ident.Obj && ident.Obj.Pos() != ident.Pos()
+	}
+
+	refs := make([]Reference, 0)
+	addRef := func(pkg, name string, ident *ast.Ident) {
+		pos := fset.Position(ident.Pos())
+		refs = append(refs, Reference{
+			Package: pkg,
+			Name:    name,
+			Line:    pos.Line,
+			Column:  pos.Column,
+		})
+	}
+
+	// The declarations of the file are resolved once, while local identifiers are only known within their declaration.
+	r := newCallResolver(p, file)
+	for _, decl := range file.Decls {
+		r.resetLocals()
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			r.addFields(fn.Recv)
+			r.addFields(fn.Type.Params)
+			r.addFields(fn.Type.Results)
+		}
+
+		var visit func(n ast.Node) bool
+		visit = func(n ast.Node) bool {
+			r.declare(n)
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				// Method names are not package-level identifiers, so skip the name of every function.
+				if n.Recv != nil {
+					ast.Inspect(n.Recv, visit)
+				}
+				ast.Inspect(n.Type, visit)
+				if n.Body != nil {
+					ast.Inspect(n.Body, visit)
+				}
+				return false
+
+			case *ast.SelectorExpr:
+				if ident, ok := n.X.(*ast.Ident); ok && unresolved[ident] {
+					if importPath, ok := r.imports[ident.Name]; ok {
+						addRef(importPath, n.Sel.Name, n.Sel)
+						return false
+					}
+				}
+				if pkg, typ, ok := r.splitType(r.exprType(n.X)); ok {
+					addRef(pkg, typ+"."+n.Sel.Name, n.Sel)
+				} else if ident, ok := n.X.(*ast.Ident); ok && isPackageLevel(ident) {
+					// Method expressions (eg. T.Method).
+					if _, ok := r.types[ident.Name]; ok {
+						addRef("", ident.Name+"."+n.Sel.Name, n.Sel)
+					}
+				}
+				ast.Inspect(n.X, visit)
+				return false
+
+			case *ast.Ident:
+				if isPackageLevel(n) {
+					addRef("", n.Name, n)
+				}
+			}
+			return true
+		}
+		ast.Inspect(decl, visit)
+	}
+	return refs, nil
+}
+
+// splitType returns the import path of the package declaring a named type and the name of the type,
+// with an empty import path for types from the package being parsed (eg. *Doer or sync.WaitGroup).
+// It returns false if the type is unknown or is not a named type.
+func (r *callResolver) splitType(typ string) (string, string, bool) {
+	typ = strings.TrimLeft(typ, "*")
+	if i := strings.Index(typ, "["); i >= 0 {
+		typ = typ[:i]
+	}
+	if typ == "" {
+		return "", "", false
+	}
+	if qualifier, name, ok := strings.Cut(typ, "."); ok {
+		importPath, ok := r.imports[qualifier]
+		return importPath, name, ok
+	}
+	return "", typ, true
+}
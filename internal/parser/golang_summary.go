@@ -0,0 +1,341 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// builtinFuncs are the predeclared Go functions. Calls to these are not reported in summaries.
+var builtinFuncs = map[string]struct{}{
+	"append": {}, "cap": {}, "clear": {}, "close": {}, "complex": {}, "copy": {}, "delete": {}, "imag": {},
+	"len": {}, "make": {}, "max": {}, "min": {}, "new": {}, "panic": {}, "print": {}, "println": {},
+	"real": {}, "recover": {},
+}
+
+var (
+	// majorVersionPattern matches a major version path element (eg. "v2" in "example.com/mod/v2").
+	majorVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+	// versionSuffixPattern matches a gopkg.in style version suffix (eg. ".v3" in "gopkg.in/yaml.v3").
+	versionSuffixPattern = regexp.MustCompile(`\.v[0-9]+$`)
+)
+
+// summarizeFunc describes the structure of the body of a function or method.
+// Calls are resolved without type information: package functions, methods on the receiver,
+// parameters and explicitly typed variables are qualified, while other calls are reported as written.
+func (p *GoParser) summarizeFunc(fn *ast.FuncDecl, fset *token.FileSet, file *ast.File) *FuncSummary {
+	summary := &FuncSummary{
+		Complexity: 1,
+		Lines:      fset.Position(fn.End()).Line - fset.Position(fn.Pos()).Line + 1,
+	}
+	if fn.Body == nil {
+		return summary
+	}
+
+	r := newCallResolver(p, file)
+	r.addFields(fn.Recv)
+	r.addFields(fn.Type.Params)
+	r.addFields(fn.Type.Results)
+
+	seen := make(map[string]struct{})
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		r.declare(n)
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			summary.Complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				summary.Complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				summary.Complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				summary.Complexity++
+			}
+		case *ast.GoStmt:
+			summary.Goroutines = true
+		case *ast.DeferStmt:
+			summary.Defer = true
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok && ident.Name == "recover" && !r.isLocal(ident.Name) {
+				summary.Recover = true
+			}
+			name := r.resolve(n.Fun)
+			if _, ok := seen[name]; name != "" && !ok {
+				seen[name] = struct{}{}
+				summary.Calls = append(summary.Calls, name)
+			}
+		}
+		return true
+	})
+	return summary
+}
+
+// callResolver resolves the names of called functions within a single function body.
+type callResolver struct {
+	parser  *GoParser
+	pkg     string                       // Name of the package being parsed
+	imports map[string]string            // Import names to import paths
+	types   map[string]map[string]string // Types declared in the file to the types of their struct fields
+	funcs   map[string][]string          // Functions declared in the file to their result types
+	locals  map[string]string            // Local identifiers to their type, if known
+}
+
+func newCallResolver(p *GoParser, file *ast.File) *callResolver {
+	r := &callResolver{
+		parser:  p,
+		pkg:     file.Name.Name,
+		imports: importNames(file),
+		types:   make(map[string]map[string]string),
+		funcs:   make(map[string][]string),
+		locals:  make(map[string]string),
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			var results []string
+			if fn.Type.Results != nil {
+				for _, field := range fn.Type.Results.List {
+					for range max(len(field.Names), 1) {
+						results = append(results, p.typeToString(field.Type))
+					}
+				}
+			}
+			r.funcs[fn.Name.Name] = results
+		}
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				fields := make(map[string]string)
+				if structType, ok := typeSpec.Type.(*ast.StructType); ok && structType.Fields != nil {
+					for _, field := range structType.Fields.List {
+						for _, name := range field.Names {
+							fields[name.Name] = p.typeToString(field.Type)
+						}
+					}
+				}
+				r.types[typeSpec.Name.Name] = fields
+			}
+		}
+	}
+	return r
+}
+
+// resetLocals forgets the local identifiers, before resolving another declaration of the file.
+func (r *callResolver) resetLocals() {
+	clear(r.locals)
+}
+
+func (r *callResolver) addFields(fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			r.locals[name.Name] = r.parser.typeToString(field.Type)
+		}
+	}
+}
+
+// declare records the local identifiers declared by the node, along with their types where known.
+func (r *callResolver) declare(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.RangeStmt:
+		if n.Tok == token.DEFINE {
+			r.addIdents("", n.Key, n.Value)
+		}
+	case *ast.FuncLit:
+		r.addFields(n.Type.Params)
+		r.addFields(n.Type.Results)
+	case *ast.AssignStmt:
+		if n.Tok != token.DEFINE {
+			return
+		}
+		var results []string
+		if len(n.Rhs) == 1 {
+			results = r.resultTypes(n.Rhs[0])
+		}
+		for i, lhs := range n.Lhs {
+			typ := ""
+			if len(n.Lhs) == len(n.Rhs) {
+				typ = r.inferType(n.Rhs[i])
+			} else if len(n.Lhs) == len(results) {
+				typ = results[i]
+			}
+			r.addIdents(typ, lhs)
+		}
+	case *ast.ValueSpec:
+		for i, name := range n.Names {
+			typ := ""
+			if n.Type != nil {
+				typ = r.parser.typeToString(n.Type)
+			} else if len(n.Names) == len(n.Values) {
+				typ = r.inferType(n.Values[i])
+			}
+			r.addIdents(typ, name)
+		}
+	}
+}
+
+// inferType returns the type of simple expressions such as composite literals (T{}, &T{}), new(T),
+// and calls to functions declared in the file with a single result.
+func (r *callResolver) inferType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return r.parser.typeToString(e.Type)
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return r.inferType(e.X)
+		}
+	case *ast.CallExpr:
+		if ident, ok := e.Fun.(*ast.Ident); ok && ident.Name == "new" && len(e.Args) == 1 {
+			return r.parser.typeToString(e.Args[0])
+		}
+		if results := r.resultTypes(e); len(results) == 1 {
+			return results[0]
+		}
+	}
+	return ""
+}
+
+// resultTypes returns the result types of a call to a function declared in the file.
+func (r *callResolver) resultTypes(expr ast.Expr) []string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok || r.isLocal(ident.Name) {
+		return nil
+	}
+	return r.funcs[ident.Name]
+}
+
+func (r *callResolver) addIdents(typ string, exprs ...ast.Expr) {
+	for _, expr := range exprs {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name != "_" {
+			r.locals[ident.Name] = typ
+		}
+	}
+}
+
+func (r *callResolver) isLocal(name string) bool {
+	_, ok := r.locals[name]
+	return ok
+}
+
+// resolve returns the qualified name of the called function, or an empty string if the call
+// should not be reported (eg. builtins, conversions and function literals).
+func (r *callResolver) resolve(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.ParenExpr:
+		return r.resolve(f.X)
+	case *ast.IndexExpr:
+		return r.resolve(f.X)
+	case *ast.IndexListExpr:
+		return r.resolve(f.X)
+
+	case *ast.Ident:
+		if r.isLocal(f.Name) {
+			return ""
+		}
+		if _, ok := builtinFuncs[f.Name]; ok {
+			return ""
+		}
+		if _, ok := predeclaredTypes[f.Name]; ok {
+			return ""
+		}
+		if _, ok := r.types[f.Name]; ok {
+			return ""
+		}
+		return r.pkg + "." + f.Name
+
+	case *ast.SelectorExpr:
+		if ident, ok := f.X.(*ast.Ident); ok && !r.isLocal(ident.Name) {
+			if importPath, ok := r.imports[ident.Name]; ok {
+				return importPath + "." + f.Sel.Name
+			}
+		}
+		if typ := r.exprType(f.X); typ != "" {
+			return r.qualifyType(typ) + "." + f.Sel.Name
+		}
+		x := r.parser.typeToString(f.X)
+		if strings.Contains(x, "<unknown>") {
+			return ""
+		}
+		if ident, ok := f.X.(*ast.Ident); ok && !r.isLocal(ident.Name) {
+			// Package-level variables and method expressions (eg. T.Method).
+			return r.pkg + "." + x + "." + f.Sel.Name
+		}
+		return x + "." + f.Sel.Name
+	}
+	return ""
+}
+
+// exprType returns the type of a local identifier or a chain of struct field selectors
+// starting from one (eg. p.wg where p is a *Pool), or an empty string if it is unknown.
+func (r *callResolver) exprType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return r.locals[e.Name]
+	case *ast.ParenExpr:
+		return r.exprType(e.X)
+	case *ast.SelectorExpr:
+		typ := strings.TrimLeft(r.exprType(e.X), "*")
+		if fields, ok := r.types[typ]; ok {
+			return fields[e.Sel.Name]
+		}
+	}
+	return ""
+}
+
+// qualifyType returns the package-qualified name of a named type (eg. *Doer becomes main.Doer).
+func (r *callResolver) qualifyType(typ string) string {
+	typ = strings.TrimLeft(typ, "*")
+	if i := strings.Index(typ, "["); i > 0 {
+		typ = typ[:i]
+	}
+	if qualifier, name, ok := strings.Cut(typ, "."); ok {
+		if importPath, ok := r.imports[qualifier]; ok {
+			return importPath + "." + name
+		}
+		return typ
+	}
+	return r.pkg + "." + typ
+}
+
+// importNames maps the name each import is referred to by in the file to its import path.
+// Without an explicit name, the package name is assumed to be the last element of the path,
+// ignoring any major version suffix (eg. "gopkg.in/yaml.v3" and "example.com/mod/v2").
+func importNames(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else {
+			name = path.Base(importPath)
+			if dir := path.Dir(importPath); majorVersionPattern.MatchString(name) && dir != "." {
+				name = path.Base(dir)
+			}
+			name = versionSuffixPattern.ReplaceAllString(name, "")
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = importPath
+	}
+	return imports
+}
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// outputCommentPattern matches the comment introducing the expected output of an example.
+var outputCommentPattern = regexp.MustCompile(`(?is)^[[:space:]]*(unordered )?output:(.*)`)
+
+// processTestFunc classifies a function declared in a _test.go file as a "test", "benchmark",
+// "fuzz" or "example" symbol, following the naming rules of `go test`. It returns nil if the
+// function is none of these.
+func (p *GoParser) processTestFunc(fn *ast.FuncDecl, fset *token.FileSet, file *ast.File) *Symbol {
+	if fn.Recv != nil || fn.Body == nil {
+		return nil
+	}
+
+	symbolType := ""
+	switch {
+	case p.isTestFunc(fn, "Test", "T") && fn.Name.Name != "TestMain":
+		symbolType = "test"
+	case p.isTestFunc(fn, "Benchmark", "B"):
+		symbolType = "benchmark"
+	case p.isTestFunc(fn, "Fuzz", "F"):
+		symbolType = "fuzz"
+	case isTestName(fn.Name.Name, "Example") && fn.Type.Params.NumFields() == 0 && fn.Type.Results.NumFields() == 0:
+		symbolType = "example"
+	default:
+		return nil
+	}
+
+	symbol := &Symbol{
+		Type:      symbolType,
+		Name:      fn.Name.Name,
+		Signature: p.getFunctionSignature(fn),
+		Docstring: p.getDocstring(fn.Doc),
+		Line:      fset.Position(fn.Name.Pos()).Line,
+	}
+
+	if symbolType == "example" {
+		setMetadata(symbol, "documents", exampleTarget(fn.Name.Name))
+		if output, unordered, ok := p.getExampleOutput(fn, file); ok {
+			setMetadata(symbol, "output", output)
+			if unordered {
+				setMetadata(symbol, "unordered", true)
+			}
+		}
+		return symbol
+	}
+
+	symbol.Children = p.collectSubtests(fn.Body, fset, fn.Name.Name+"/")
+	return symbol
+}
+
+// isTestFunc reports whether fn is named with the given prefix and accepts a single
+// parameter of the given type from the testing package (eg. *testing.T).
+func (p *GoParser) isTestFunc(fn *ast.FuncDecl, prefix, param string) bool {
+	if !isTestName(fn.Name.Name, prefix) || fn.Type.Results.NumFields() != 0 {
+		return false
+	}
+	params := fn.Type.Params
+	if params.NumFields() != 1 {
+		return false
+	}
+	star, ok := params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == param
+}
+
+// isTestName reports whether name is the prefix alone or the prefix followed
+// by a character which is not a lowercase letter (eg. TestFoo but not Testify).
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// exampleTarget returns the name of the symbol documented by an example function, following
+// the conventions of `go doc`: Example documents the package, ExampleF documents F, ExampleT_M
+// documents T.M, and any trailing suffix starting with a lowercase letter is ignored.
+func exampleTarget(name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "Example"), "_")
+	if last := parts[len(parts)-1]; len(parts) > 1 && last != "" {
+		if r, _ := utf8.DecodeRuneInString(last); unicode.IsLower(r) {
+			parts = parts[:len(parts)-1]
+		}
+	}
+	if parts[0] == "" {
+		return "package"
+	}
+	return strings.Join(parts, ".")
+}
+
+// getExampleOutput returns the expected output declared by the last comment in the body of an
+// example function, and whether that output may be produced in any order.
+func (p *GoParser) getExampleOutput(fn *ast.FuncDecl, file *ast.File) (string, bool, bool) {
+	var last *ast.CommentGroup
+	for _, group := range file.Comments {
+		if group.Pos() > fn.Body.Lbrace && group.End() < fn.Body.Rbrace {
+			last = group
+		}
+	}
+	if last == nil {
+		return "", false, false
+	}
+
+	matches := outputCommentPattern.FindStringSubmatch(last.Text())
+	if matches == nil {
+		return "", false, false
+	}
+	return strings.TrimSpace(matches[2]), matches[1] != "", true
+}
+
+// collectSubtests returns the subtests started with a string literal name (eg. t.Run("name", ...)).
+// Subtests are named using their full path, as reported by `go test -v` (eg. "TestX/a_b" for "a b").
+func (p *GoParser) collectSubtests(node ast.Node, fset *token.FileSet, prefix string) []*Symbol {
+	var subtests []*Symbol
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Run" || len(call.Args) != 2 {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+
+		name = prefix + rewriteSubtestName(name)
+		subtests = append(subtests, &Symbol{
+			Type: "subtest",
+			Name: name,
+			Line: fset.Position(call.Pos()).Line,
+		})
+		subtests = append(subtests, p.collectSubtests(call.Args[1], fset, name+"/")...)
+		return false
+	})
+	return subtests
+}
+
+// rewriteSubtestName rewrites a subtest name as the testing package does, replacing spaces with underscores
+// and escaping unprintable characters (eg. "a b\n" becomes "a_b_" and "\x00" becomes "\\x00").
+func rewriteSubtestName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case isTestSpace(r):
+			sb.WriteByte('_')
+		case !strconv.IsPrint(r):
+			quoted := strconv.QuoteRune(r)
+			sb.WriteString(quoted[1 : len(quoted)-1])
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// isTestSpace reports whether the testing package replaces the character within subtest names, which is not
+// the same as the Unicode space class.
+func isTestSpace(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', ' ', 0x85, 0xA0, 0x1680, 0x2028, 0x2029, 0x202f, 0x205f, 0x3000:
+		return true
+	}
+	return r >= 0x2000 && r <= 0x200a
+}
@@ -1,7 +1,10 @@
 // Package parser provides language-specific parsing capabilities
 package parser
 
-import "path/filepath"
+import (
+	"fmt"
+	"path/filepath"
+)
 
 // Parser defines the interface for language-specific parsers
 type Parser interface {
@@ -21,15 +24,66 @@ type Symbol struct {
 	Decorators []string       // Any decorators/annotations
 	Children   []*Symbol      // Nested symbols (e.g., methods in a class)
 	Metadata   map[string]any // Additional language-specific metadata
+	Line       int            // Line on which the symbol is declared, starting at 1 (0 if unknown)
+	EndLine    int            // Line on which the declaration ends (0 if unknown)
+	Start      int            // Byte offset of the declaration, including its documentation
+	End        int            // Byte offset immediately after the declaration (0 if unknown)
+}
+
+// ReferenceFinder is implemented by parsers which can resolve the symbols referenced within a file.
+// References for languages without a ReferenceFinder are found by matching symbol names.
+type ReferenceFinder interface {
+	// FindReferences returns the references to symbols declared at the top level of a package or file
+	FindReferences(content []byte, filename string) ([]Reference, error)
+}
+
+// Reference represents the use of a symbol within a file
+type Reference struct {
+	Package string // Package declaring the symbol (eg. an import path), or empty for the package of the file
+	Name    string // Name of the symbol as it appears in outlines, with methods qualified by their type
+	Line    int    // Line number, starting at 1
+	Column  int    // Column number in bytes, starting at 1
+}
+
+// FuncSummary describes the structure of the body of a function or method
+type FuncSummary struct {
+	Complexity int      `json:"complexity"`           // Cyclomatic complexity
+	Lines      int      `json:"lines"`                // Number of lines spanned by the declaration
+	Calls      []string `json:"calls,omitempty"`      // Called functions/methods, package-qualified where possible
+	Goroutines bool     `json:"goroutines,omitempty"` // Whether the body spawns goroutines
+	Defer      bool     `json:"defer,omitempty"`      // Whether the body uses defer
+	Recover    bool     `json:"recover,omitempty"`    // Whether the body calls recover
 }
 
 // FileOutline represents the parsed structure of a source file
 type FileOutline struct {
 	Filename string    // Name of the parsed file
+	Package  string    // Name of the package or module declared by the file, if any
+	Header   int       // Byte offset immediately after the file header (eg. the package clause and imports)
 	Symbols  []*Symbol // Top-level symbols in the file
 	Errors   []error   // Any errors encountered during parsing
 }
 
+// setMetadata sets a metadata value on the symbol, creating the metadata map if needed.
+func setMetadata(symbol *Symbol, key string, value any) {
+	if symbol.Metadata == nil {
+		symbol.Metadata = make(map[string]any)
+	}
+	symbol.Metadata[key] = value
+}
+
+// ParseError represents a problem found at a specific position while parsing a file
+type ParseError struct {
+	Filename string // Name of the parsed file
+	Line     int    // Line number, starting at 1
+	Column   int    // Column number in bytes, starting at 1
+	Message  string // Description of the problem
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Message)
+}
+
 // Registry manages the available parsers
 type Registry struct {
 	parsers map[string]Parser
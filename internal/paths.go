@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TraversePaths collects the files of several directories and individual files, merged into one tree. As with
+// TraverseDirectory, the files of a directory are relative to its parent, so each directory is a distinct root.
+// Individual files are relative to the working directory when within it (eg. "internal/output.go"), or named
+// alone otherwise. They are selected as if the working directory were traversed, so that the patterns are matched
+// against the same path and the directories containing them must be walked (eg. hidden directories are not).
+// Files outside the working directory are selected within their repository, or their directory if there is none.
+func TraversePaths(paths []string, opts TraverseOptions) ([]PathInfo, []SkippedPath, error) {
+	// The patterns are checked before any of the paths.
+	if _, err := compilePathSelector(opts); err != nil {
+		return nil, nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting working directory: %w", err)
+	}
+
+	files := make([]PathInfo, 0)
+	skipped := make([]SkippedPath, 0)
+	selectors := make(map[string]*pathSelector) // Selectors of the individual files, by root directory
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting absolute path for %q: %w", path, err)
+		}
+		// Files are named relative to the working directory when within it.
+		relPath, inWorkDir := filepath.Base(absPath), false
+		if rel, err := filepath.Rel(wd, absPath); err == nil && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			relPath, inWorkDir = filepath.ToSlash(rel), true
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			// Paths which no longer exist (eg. stale entries of --files-from) are skipped, unless strict.
+			if err := opts.Warnings.Add(absPath, relPath, err); err != nil {
+				return nil, nil, fmt.Errorf("describing path %q: %w", path, err)
+			}
+			continue
+		}
+
+		if info.IsDir() {
+			dirFiles, dirSkipped, err := traverseFiles(absPath, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, dirFiles...)
+			skipped = append(skipped, dirSkipped...)
+			continue
+		}
+
+		// Files are selected as within the working directory, or their repository (or directory) when outside it.
+		root := wd
+		if !inWorkDir {
+			root, _ = findRepository(filepath.Dir(absPath))
+		}
+		s, ok := selectors[root]
+		if !ok {
+			s, _, err = newPathSelector(root, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			selectors[root] = s
+		}
+
+		matchPath, err := filepath.Rel(s.basePath, absPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting relative path between %q and %q: %w", s.basePath, absPath, err)
+		}
+		matchPath = filepath.ToSlash(matchPath)
+		decision, _, err := s.selectAncestors(matchPath)
+		if err == nil && decision.Included {
+			decision, err = s.selectFile(absPath, matchPath, fs.FileInfoToDirEntry(info))
+		}
+		if err != nil {
+			if err := opts.Warnings.Add(absPath, relPath, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		switch {
+		case decision.Included:
+			files = append(files, PathInfo{
+				Path:         absPath,
+				RelativePath: relPath,
+				Depth:        strings.Count(relPath, "/") + 1,
+				Class:        decision.Classification.Class,
+				Language:     decision.Language,
+			})
+		case decision.Skipped != "":
+			skipped = append(skipped, SkippedPath{Path: absPath, RelativePath: relPath, Reason: decision.Skipped})
+		}
+	}
+
+	files = mergeFiles(files)
+	err = processPaths(&files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkDistinctPaths(files); err != nil {
+		return nil, nil, err
+	}
+	return files, skipped, nil
+}
+
+// mergeFiles removes the files which were collected more than once (eg. from a directory and individually),
+// keeping the first.
+func mergeFiles(files []PathInfo) []PathInfo {
+	seen := make(map[string]bool, len(files))
+	merged := make([]PathInfo, 0, len(files))
+	for _, file := range files {
+		if seen[file.Path] {
+			continue
+		}
+		seen[file.Path] = true
+		merged = append(merged, file)
+	}
+	return merged
+}
+
+// checkDistinctPaths returns an error if different paths would be shown at the same place in the tree (eg. two
+// directories named "pkg").
+func checkDistinctPaths(paths []PathInfo) error {
+	byRelPath := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if other, ok := byRelPath[path.RelativePath]; ok && other != path.Path {
+			return fmt.Errorf("paths %q and %q would both be shown as %q", other, path.Path, path.RelativePath)
+		}
+		byRelPath[path.RelativePath] = path.Path
+	}
+	return nil
+}
+
+// ReadPathList reads a list of paths separated by newlines, or by NUL characters if there are any (eg. the
+// output of 'git ls-files -z'). Empty entries are ignored.
+func ReadPathList(r io.Reader) ([]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	separator := "\n"
+	if bytes.IndexByte(content, 0) >= 0 {
+		separator = "\x00"
+		// A newline after the last path is not part of it (eg. when the list is followed by 'echo').
+		content = bytes.TrimSuffix(content, []byte("\x00\n"))
+	}
+
+	paths := make([]string, 0)
+	for _, path := range strings.Split(string(content), separator) {
+		if separator == "\n" {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraversePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFiles := []string{
+		"project/app/main.go",
+		"project/app/README.md",
+		"project/lib/lib.go",
+		"project/tools/gen.go",
+		"project/.github/workflows/ci.go",
+		"other/pkg/pkg.go",
+		"another/pkg/pkg.go",
+	}
+	for _, path := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, nil, 0644))
+	}
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(tmpDir, "project")))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	tests := map[string]struct {
+		paths        []string
+		filter       []string
+		wantRelPaths []string
+		wantErr      string
+	}{
+		"directories and files": {
+			paths: []string{"app", "tools/gen.go", filepath.Join(tmpDir, "other", "pkg")},
+			wantRelPaths: []string{
+				"app/", "app/main.go",
+				"tools/", "tools/gen.go",
+				"pkg/", "pkg/pkg.go",
+			},
+		},
+		"file outside the working directory": {
+			paths:        []string{filepath.Join(tmpDir, "other", "pkg", "pkg.go")},
+			wantRelPaths: []string{"pkg.go"},
+		},
+		"filtered file": {
+			paths:        []string{"app/README.md", "lib/lib.go"},
+			wantRelPaths: []string{"lib/", "lib/lib.go"},
+		},
+		"pattern anchored to a directory": {
+			paths:        []string{"lib/lib.go", "tools/gen.go"},
+			filter:       []string{"lib/*.go"},
+			wantRelPaths: []string{"lib/", "lib/lib.go"},
+		},
+		"file within hidden directory": {
+			paths:        []string{".github/workflows/ci.go", "tools/gen.go"},
+			filter:       []string{"*", "!.*"},
+			wantRelPaths: []string{"tools/", "tools/gen.go"},
+		},
+		"repeated": {
+			paths:        []string{"lib", "lib/lib.go"},
+			wantRelPaths: []string{"lib/", "lib/lib.go"},
+		},
+		"conflicting roots": {
+			paths:   []string{filepath.Join(tmpDir, "other", "pkg"), filepath.Join(tmpDir, "another", "pkg")},
+			wantErr: `would both be shown as "pkg/pkg.go"`,
+		},
+		"missing path": {
+			paths:   []string{"missing.go"},
+			wantErr: `describing path "missing.go"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter := tt.filter
+			if filter == nil {
+				filter = []string{"**/*.go"}
+			}
+			paths, _, err := TraversePaths(tt.paths, TraverseOptions{FilterPatterns: filter})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			gotPaths := make([]string, 0)
+			for _, p := range paths {
+				if p.IsDir {
+					gotPaths = append(gotPaths, p.RelativePath+"/")
+				} else {
+					gotPaths = append(gotPaths, p.RelativePath)
+				}
+			}
+			assert.ElementsMatch(t, tt.wantRelPaths, gotPaths)
+		})
+	}
+}
+
+func TestReadPathList(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  []string
+	}{
+		"newlines":        {input: "main.go\ninternal/output.go\n", want: []string{"main.go", "internal/output.go"}},
+		"CRLF":            {input: "main.go\r\ngo.mod\r\n", want: []string{"main.go", "go.mod"}},
+		"NUL characters":  {input: "my file.go\x00new\nline.go\x00", want: []string{"my file.go", "new\nline.go"}},
+		"NUL and newline": {input: "main.go\x00go.mod\x00\n", want: []string{"main.go", "go.mod"}},
+		"empty entries":   {input: "\nmain.go\n\n", want: []string{"main.go"}},
+		"no trailing end": {input: "main.go", want: []string{"main.go"}},
+		"empty":           {input: "", want: []string{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ReadPathList(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Broderick-Westrope/amalgo/internal/parser"
+)
+
+// FileReferences lists where the symbols outlined for a file are referenced
+type FileReferences struct {
+	Path    PathInfo
+	Package string // Name of the package or module declared by the file, if any
+	Symbols []*SymbolReferences
+}
+
+// SymbolReferences lists where a symbol is referenced within the selected files
+type SymbolReferences struct {
+	Symbol     *parser.Symbol
+	References []ReferenceLocation
+}
+
+// ReferenceLocation represents the position of a reference within a file
+type ReferenceLocation struct {
+	Path   string // Relative path of the referencing file
+	Line   int
+	Column int
+}
+
+// referencedFile is a selected file with an available parser.
+type referencedFile struct {
+	path    PathInfo
+	index   int // Position of the file in the selected paths, used to order references
+	content []byte
+	parser  parser.Parser
+	pkg     string // Identifies the package of the file (eg. the Go import path)
+	refs    *FileReferences
+}
+
+// GenerateReferences builds an index of where each outlined symbol is referenced within the selected files.
+// References are resolved by parsers which implement parser.ReferenceFinder, and otherwise found by
+// matching whole words in files handled by the same parser. Only symbols declared at the top level
+// of a file are indexed, along with methods and enum members. Files which cannot be read are collected
+// by the warnings and left out of the index.
+func GenerateReferences(paths []PathInfo, registry *parser.Registry, warnings *Warnings) ([]*FileReferences, error) {
+	modules := newModuleResolver()
+	files := make([]*referencedFile, 0)
+	for i, path := range paths {
+		if path.IsDir || path.Link != "" || !registry.IsSupported(path.Path) {
+			continue
+		}
+
+		content, err := os.ReadFile(path.Path)
+		if err != nil {
+			if err := warnings.Add(path.Path, path.RelativePath, err); err != nil {
+				return nil, fmt.Errorf("reading file %q: %w", path.Path, err)
+			}
+			continue
+		}
+		pkg, err := modules.importPath(filepath.Dir(path.Path))
+		if err != nil {
+			return nil, fmt.Errorf("resolving package of %q: %w", path.Path, err)
+		}
+		if pkg == "" {
+			pkg = filepath.Dir(path.Path)
+		}
+
+		files = append(files, &referencedFile{
+			path:    path,
+			index:   i,
+			content: content,
+			parser:  registry.GetParser(path.Path),
+			pkg:     pkg,
+			refs:    &FileReferences{Path: path},
+		})
+	}
+
+	// Index the symbols declared by each file by their package and name.
+	symbols := make(map[string]*SymbolReferences)
+	declaredIn := make(map[*SymbolReferences]*referencedFile)
+	for _, file := range files {
+		outline, err := file.parser.Parse(file.content, file.path.Path)
+		if err != nil {
+			return nil, fmt.Errorf("parsing file %q: %w", file.path.Path, err)
+		}
+		file.refs.Package = outline.Package
+
+		isGo := filepath.Ext(file.path.Path) == ".go"
+		if isGo && strings.HasSuffix(outline.Package, "_test") {
+			// External test packages (eg. store_test) are separate from the package in the same directory.
+			file.pkg += "_test"
+		}
+		for _, symbol := range indexedSymbols(outline.Symbols) {
+			if isGo && symbol.Type == "function" && symbol.Name == "init" {
+				// A Go package may declare several init functions, none of which can be referenced.
+				continue
+			}
+			symbolRefs := &SymbolReferences{Symbol: symbol}
+			file.refs.Symbols = append(file.refs.Symbols, symbolRefs)
+			symbols[referenceKey(file.pkg, symbol.Name)] = symbolRefs
+			declaredIn[symbolRefs] = file
+		}
+	}
+
+	// Resolve references using the parsers where possible.
+	byParser := make(map[parser.Parser][]*referencedFile)
+	for _, file := range files {
+		finder, ok := file.parser.(parser.ReferenceFinder)
+		if !ok {
+			byParser[file.parser] = append(byParser[file.parser], file)
+			continue
+		}
+
+		refs, err := finder.FindReferences(file.content, file.path.Path)
+		if err != nil {
+			return nil, fmt.Errorf("finding references in %q: %w", file.path.Path, err)
+		}
+		for _, ref := range refs {
+			pkg := ref.Package
+			if pkg == "" {
+				pkg = file.pkg
+			}
+			symbolRefs, ok := symbols[referenceKey(pkg, ref.Name)]
+			if !ok {
+				continue
+			}
+			symbolRefs.References = append(symbolRefs.References, ReferenceLocation{
+				Path:   file.path.RelativePath,
+				Line:   ref.Line,
+				Column: ref.Column,
+			})
+		}
+	}
+
+	// Otherwise, match symbol names against the files handled by the same parser.
+	for _, file := range files {
+		if _, ok := file.parser.(parser.ReferenceFinder); ok {
+			continue
+		}
+		for _, symbolRefs := range file.refs.Symbols {
+			name := symbolRefs.Symbol.Name
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+
+			for _, other := range byParser[file.parser] {
+				for lineIdx, line := range strings.Split(string(other.content), "\n") {
+					if other == file && lineIdx+1 == symbolRefs.Symbol.Line {
+						// Skip the declaration.
+						continue
+					}
+					for _, loc := range pattern.FindAllStringIndex(line, -1) {
+						symbolRefs.References = append(symbolRefs.References, ReferenceLocation{
+							Path:   other.path.RelativePath,
+							Line:   lineIdx + 1,
+							Column: loc[0] + 1,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	// Order references by the position of the referencing file in the selected paths.
+	order := make(map[string]int, len(files))
+	for _, file := range files {
+		order[file.path.RelativePath] = file.index
+	}
+	result := make([]*FileReferences, 0, len(files))
+	for _, file := range files {
+		for _, symbolRefs := range file.refs.Symbols {
+			slices.SortStableFunc(symbolRefs.References, func(a, b ReferenceLocation) int {
+				if order[a.Path] != order[b.Path] {
+					return order[a.Path] - order[b.Path]
+				}
+				if a.Line != b.Line {
+					return a.Line - b.Line
+				}
+				return a.Column - b.Column
+			})
+		}
+		result = append(result, file.refs)
+	}
+	return result, nil
+}
+
+// indexedSymbols returns the symbols which may be referenced from other files:
+// top-level symbols and the members of enums.
+func indexedSymbols(symbols []*parser.Symbol) []*parser.Symbol {
+	result := make([]*parser.Symbol, 0, len(symbols))
+	for _, symbol := range symbols {
+		if symbol.Name == "_" {
+			continue
+		}
+		result = append(result, symbol)
+		if symbol.Type == "enum" {
+			result = append(result, symbol.Children...)
+		}
+	}
+	return result
+}
+
+// referenceKey identifies a symbol by its package and name.
+func referenceKey(pkg, name string) string {
+	return pkg + "\x00" + plainSymbolName(name)
+}
+
+// plainSymbolName removes pointer receivers and type parameters from the name of
+// a symbol (eg. *List[T].Push becomes List.Push).
+func plainSymbolName(name string) string {
+	name = strings.TrimLeft(name, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		if j := strings.LastIndex(name, "]"); j > i {
+			name = name[:i] + name[j+1:]
+		}
+	}
+	return name
+}
+
+// writeReferences creates a textual representation of the reference index,
+// listing the references to each symbol grouped by the file declaring it.
+func writeReferences(files []*FileReferences) string {
+	var sb strings.Builder
+	for _, file := range files {
+		if len(file.Symbols) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### File: %s\n\n", file.Path.RelativePath))
+		for _, symbolRefs := range file.Symbols {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", strings.ToUpper(symbolRefs.Symbol.Type), symbolRefs.Symbol.Name))
+			if len(symbolRefs.References) == 0 {
+				sb.WriteString("  < no references >\n")
+			}
+			for _, ref := range symbolRefs.References {
+				sb.WriteString(fmt.Sprintf("  %s:%d:%d\n", ref.Path, ref.Line, ref.Column))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Broderick-Westrope/amalgo/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateReferences(t *testing.T) {
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n",
+		"shop.go": `package shop
+
+func init() {}
+
+func helper() int { return 1 }
+
+func New() int { return helper() }
+`,
+		"setup.go": `package shop
+
+func init() {}
+`,
+		"shop_test.go": `package shop_test
+
+import (
+	"testing"
+
+	"example.com/shop"
+)
+
+func helper() int { return 2 }
+
+func TestNew(t *testing.T) {
+	_ = helper() + shop.New()
+}
+`,
+	}
+	tmpDir := t.TempDir()
+	var paths []PathInfo
+	for _, name := range []string{"go.mod", "shop.go", "setup.go", "shop_test.go"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(files[name]), 0644))
+		paths = append(paths, PathInfo{Path: path, RelativePath: name, Depth: 1})
+	}
+
+	registry := parser.NewRegistry()
+	registry.Register(parser.NewGoParser(parser.GoParserOptions{}))
+	result, err := GenerateReferences(paths, registry, nil)
+	require.NoError(t, err)
+
+	got := make(map[string][]ReferenceLocation)
+	for _, file := range result {
+		for _, symbolRefs := range file.Symbols {
+			got[file.Path.RelativePath+":"+symbolRefs.Symbol.Name] = symbolRefs.References
+		}
+	}
+
+	tests := map[string]struct {
+		symbol string
+		want   []ReferenceLocation
+	}{
+		"helper of the package": {
+			symbol: "shop.go:helper",
+			want:   []ReferenceLocation{{Path: "shop.go", Line: 7, Column: 25}},
+		},
+		"helper of the external test package": {
+			symbol: "shop_test.go:helper",
+			want:   []ReferenceLocation{{Path: "shop_test.go", Line: 12, Column: 6}},
+		},
+		"function referenced by the external test package": {
+			symbol: "shop.go:New",
+			want:   []ReferenceLocation{{Path: "shop_test.go", Line: 12, Column: 22}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Contains(t, got, tt.symbol)
+			assert.Equal(t, tt.want, got[tt.symbol])
+		})
+	}
+
+	t.Run("init functions are not indexed", func(t *testing.T) {
+		assert.NotContains(t, got, "shop.go:init")
+		assert.NotContains(t, got, "setup.go:init")
+	})
+}
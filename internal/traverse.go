@@ -6,7 +6,9 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/Broderick-Westrope/amalgo/pkg/filter"
 )
@@ -17,91 +19,546 @@ type PathInfo struct {
 	RelativePath string
 	Depth        int
 	IsDir        bool
+	Class        FileClass // Class of generated, vendored, and lock files
+	Language     string    // Detected language of a file, if known (see DetectLanguage)
+	Link         string    // Target of a recorded symbolic link, whose contents are not dumped
 }
 
-// TraverseDirectory traverses the directory and collects path information using the filter package
-func TraverseDirectory(dir string, filterPatterns, gitignorePaths []string) ([]PathInfo, error) {
+// SkippedPath represents a file which was selected by the filters but skipped, and why
+type SkippedPath struct {
+	Path         string
+	RelativePath string
+	Reason       string
+}
+
+// TraverseOptions configures which paths are collected when traversing a directory
+type TraverseOptions struct {
+	FilterPatterns   []string
+	GitignorePaths   []string  // Gitignore files whose patterns are anchored to the traversed directory
+	NoIgnoreFiles    bool      // Skip discovering .amalgoignore files within the traversed directory
+	RespectGitignore bool      // Discover the gitignore files of the repository, as git would
+	MaxSize          ByteSize  // Skip files larger than this size, unless zero
+	MinSize          ByteSize  // Skip files smaller than this size, unless zero
+	ModifiedSince    time.Time // Skip files last modified before this time, unless zero
+	MaxLines         int       // Skip files with more lines than this, unless zero
+	Contains         []string  // Regular expressions of which a file's contents must match any
+	NotContains      []string  // Regular expressions of which a file's contents must match none
+	ExcludeClasses   []FileClass
+	Languages        []string // Include only files of these languages, unless empty
+	ExcludeLanguages []string
+	Symlinks         SymlinkPolicy
+	Git              GitSelection // Select only these files of the git repository, unless its mode is empty
+	Warnings         *Warnings    // Collects the paths which cannot be read, unless nil
+}
+
+// SymlinkPolicy decides how symbolic links are handled when traversing a directory
+type SymlinkPolicy string
+
+const (
+	SymlinksSkip   = "skip"
+	SymlinksFollow = "follow"
+	SymlinksRecord = "record"
+)
+
+// Decision describes why a path is included or excluded
+type Decision struct {
+	Included bool
+	Pattern  *filter.Pattern // Deciding pattern, which is nil when decided by default
+	Default  string          // Description of the default used when no pattern decided
+	Skipped  string          // Reason a file selected by the patterns is skipped (eg. its size)
+	Excluded string          // Reason a file selected by the patterns is excluded (eg. its contents)
+
+	Classification Classification
+	Language       string
+	Link           string // Target of a recorded symbolic link
+}
+
+// ignoreFileName is the name of the files discovered within the traversed directory, whose patterns
+// exclude the paths they match.
+const ignoreFileName = ".amalgoignore"
+
+const (
+	defaultNoFilterMatch = "not matched by any filter pattern"
+	defaultGitDirectory  = "git directory"
+)
+
+// pathSelector decides which paths within the traversed directory are collected.
+type pathSelector struct {
+	opts       TraverseOptions
+	basePath   string
+	filter     *filter.Filter
+	gitignore  *filter.Filter    // Patterns of the gitignore files and .amalgoignore files, in order of precedence
+	ignoreDirs map[string]bool   // Directories whose .amalgoignore files have been loaded
+	repoIgnore *gitignoreMatcher // Only set when respecting the repository's gitignore files
+	contents   *contentMatcher   // Only set when there are content patterns
+	classifier *fileClassifier
+	gitFiles   map[string]bool // Files selected from git and the directories containing them, unless nil
+}
+
+// newPathSelector creates a selector for the directory to traverse, returning it along with the
+// absolute path of the directory (see resolveBasePath).
+func newPathSelector(dir string, opts TraverseOptions) (*pathSelector, string, error) {
+	s, err := compilePathSelector(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	basePath, err := resolveBasePath(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	s.basePath = basePath
+
+	s.classifier, err = newFileClassifier(basePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("loading gitattributes files: %w", err)
+	}
+	if opts.RespectGitignore {
+		s.repoIgnore, err = newGitignoreMatcher(basePath)
+		if err != nil {
+			return nil, "", fmt.Errorf("loading gitignore files: %w", err)
+		}
+	}
+	if opts.Git.Mode != "" {
+		s.gitFiles, err = gitSelectedFiles(basePath, opts.Git)
+		if err != nil {
+			return nil, "", fmt.Errorf("selecting files from git: %w", err)
+		}
+	}
+	return s, basePath, nil
+}
+
+// compilePathSelector creates a selector from the patterns of the options, which is yet to be given the
+// directory to traverse.
+func compilePathSelector(opts TraverseOptions) (*pathSelector, error) {
 	// Create the filter from filter patterns.
-	f := filter.CompileFilterPatterns(filterPatterns...)
+	f, err := filter.CompileFilterPatterns(opts.FilterPatterns...)
+	if err != nil {
+		return nil, fmt.Errorf("compiling filter patterns: %w", err)
+	}
 	// Create the gitignore filter from gitignore file paths.
 	gi := new(filter.Filter)
-	for _, giPath := range gitignorePaths {
+	for _, giPath := range opts.GitignorePaths {
 		tempFilter, err := filter.CompileFilterPatternFile(giPath)
 		if err != nil {
 			return nil, fmt.Errorf("compiling patterns from gitignore file %q: %w", giPath, err)
 		}
 		gi.MergeWithPrecedence(tempFilter)
 	}
+	contents, err := newContentMatcher(opts.Contains, opts.NotContains)
+	if err != nil {
+		return nil, fmt.Errorf("compiling content patterns: %w", err)
+	}
 
-	paths := make([]PathInfo, 0)
+	return &pathSelector{
+		opts:       opts,
+		filter:     f,
+		gitignore:  gi,
+		ignoreDirs: make(map[string]bool),
+		contents:   contents,
+	}, nil
+}
+
+// selectDir decides whether a directory should be walked, given its path relative to the traversed directory.
+// Directories are only excluded when none of their files could be included.
+func (s *pathSelector) selectDir(path, relPath string) (Decision, error) {
+	if s.gitFiles != nil && !s.gitFiles[path] {
+		return Decision{Excluded: fmt.Sprintf("no files selected by '--git %s'", s.opts.Git)}, nil
+	}
+	if all, pattern := s.gitignore.MatchesAllWithinHow(relPath); all {
+		return Decision{Pattern: pattern}, nil
+	}
+	if possible, pattern := s.filter.CouldMatchWithinHow(relPath); !possible {
+		return Decision{Pattern: pattern, Default: defaultNoFilterMatch}, nil
+	}
+	// As with git, files within ignored directories cannot be re-included.
+	if s.repoIgnore != nil {
+		ignored, pattern, err := s.repoIgnore.ignored(path, true)
+		if err != nil || ignored {
+			return Decision{Pattern: pattern, Default: defaultGitDirectory}, err
+		}
+	}
+	return Decision{Included: true}, nil
+}
+
+// selectAncestors decides whether the directories which would be walked to reach a path are walked, given its
+// path relative to the traversed directory. It returns the decision and path of the first directory which is
+// not, or an included decision once each of them has been entered.
+func (s *pathSelector) selectAncestors(relPath string) (Decision, string, error) {
+	if err := s.enterDir(s.basePath); err != nil {
+		return Decision{}, "", err
+	}
+	components := strings.Split(relPath, "/")
+	current := s.basePath
+	for i := range components[:len(components)-1] {
+		current = filepath.Join(current, components[i])
+		decision, err := s.selectDir(current, strings.Join(components[:i+1], "/"))
+		if err != nil || !decision.Included {
+			return decision, current, err
+		}
+		if err := s.enterDir(current); err != nil {
+			return Decision{}, "", err
+		}
+	}
+	return Decision{Included: true}, "", nil
+}
+
+// enterDir prepares to select the paths within a directory which is being walked.
+func (s *pathSelector) enterDir(path string) error {
+	if err := s.addIgnoreFile(path); err != nil {
+		return err
+	}
+	if err := s.classifier.attributes.addDir(path); err != nil {
+		return err
+	}
+	if s.repoIgnore == nil {
+		return nil
+	}
+	return s.repoIgnore.addDir(path)
+}
+
+// addIgnoreFile loads the .amalgoignore file of a directory, if it has one. Its patterns are anchored to the
+// directory, and take precedence over the gitignore files and those of the directories above it.
+func (s *pathSelector) addIgnoreFile(dir string) error {
+	if s.opts.NoIgnoreFiles || s.ignoreDirs[dir] {
+		return nil
+	}
+	s.ignoreDirs[dir] = true
+
+	base, err := filepath.Rel(s.basePath, dir)
+	if err != nil {
+		return fmt.Errorf("getting relative path between %q and %q: %w", s.basePath, dir, err)
+	}
+	if base == "." {
+		base = ""
+	}
+	path := filepath.Join(dir, ignoreFileName)
+	f, err := filter.CompileFilterPatternFileAt(path, filepath.ToSlash(base))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("compiling patterns from ignore file %q: %w", path, err)
+	}
+	s.gitignore.MergeWithPrecedence(f)
+	return nil
+}
+
+// selectFile decides whether a file is collected, given its path relative to the traversed directory.
+// Files selected by the patterns are then checked against the languages, the excluded classes, the
+// metadata options (eg. their size), and lastly the content patterns.
+func (s *pathSelector) selectFile(path, relPath string, d fs.DirEntry) (Decision, error) {
+	decision, err := s.selectPatterns(path, relPath)
+	if err != nil || !decision.Included {
+		return decision, err
+	}
+	pattern := decision.Pattern
+
+	language, err := DetectLanguage(path)
+	if err != nil {
+		return Decision{}, fmt.Errorf("detecting language of file %q: %w", path, err)
+	}
+	if reason := s.checkLanguage(language); reason != "" {
+		return Decision{Pattern: pattern, Excluded: reason, Language: language}, nil
+	}
+
+	classification, err := s.classifier.classify(path)
+	if err != nil {
+		return Decision{}, err
+	}
+	decision = Decision{Pattern: pattern, Classification: classification, Language: language}
+	if classification.Class != "" && slices.Contains(s.opts.ExcludeClasses, classification.Class) {
+		decision.Excluded = fmt.Sprintf("%s: %s", classification.Class, classification.Reason)
+		return decision, nil
+	}
+
+	decision.Skipped, err = checkMetadata(path, d, s.opts)
+	if err != nil || decision.Skipped != "" {
+		return decision, err
+	}
+	if s.contents != nil {
+		decision.Excluded, err = s.contents.check(path)
+		if err != nil || decision.Excluded != "" {
+			return decision, err
+		}
+	}
+	decision.Included = true
+	return decision, nil
+}
+
+// selectPatterns decides whether a file or symbolic link is selected from git, and by the ignore files and
+// filter patterns, given its path relative to the traversed directory.
+func (s *pathSelector) selectPatterns(path, relPath string) (Decision, error) {
+	if s.gitFiles != nil && !s.gitFiles[path] {
+		return Decision{Excluded: fmt.Sprintf("not selected by '--git %s'", s.opts.Git)}, nil
+	}
+	if s.repoIgnore != nil {
+		ignored, pattern, err := s.repoIgnore.ignored(path, false)
+		if err != nil || ignored {
+			return Decision{Pattern: pattern}, err
+		}
+	}
+	if matches, pattern := s.gitignore.MatchesPathHow(relPath); matches {
+		return Decision{Pattern: pattern}, nil
+	}
+	matches, pattern := s.filter.MatchesPathHow(relPath)
+	if !matches {
+		return Decision{Pattern: pattern, Default: defaultNoFilterMatch}, nil
+	}
+	return Decision{Included: true, Pattern: pattern}, nil
+}
+
+// checkLanguage returns the reason a file of the given language is excluded, or an empty string if it is not.
+func (s *pathSelector) checkLanguage(language string) string {
+	name := language
+	if name == "" {
+		name = "unknown"
+	}
+	switch {
+	case len(s.opts.Languages) > 0 && !slices.Contains(s.opts.Languages, language):
+		return fmt.Sprintf("language %s is not selected by '--lang'", name)
+	case language != "" && slices.Contains(s.opts.ExcludeLanguages, language):
+		return fmt.Sprintf("language %s is excluded by '--exclude-lang'", name)
+	}
+	return ""
+}
+
+// resolveBasePath returns the absolute path of the directory to traverse. If a file is provided
+// its parent directory is used.
+func resolveBasePath(dir string) (string, error) {
 	basePath, err := filepath.Abs(dir)
 	if err != nil {
-		return nil, fmt.Errorf("getting base path for directory %q: %w", dir, err)
+		return "", fmt.Errorf("getting base path for directory %q: %w", dir, err)
 	}
 
 	baseInfo, err := os.Stat(basePath)
 	if err != nil {
-		return nil, fmt.Errorf("describing base path for directory %q: %w", dir, err)
+		return "", fmt.Errorf("describing base path for directory %q: %w", dir, err)
 	}
 
 	if !baseInfo.IsDir() {
 		basePath = filepath.Dir(basePath)
 		baseInfo, err = os.Stat(basePath)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		if !baseInfo.IsDir() {
-			return nil, fmt.Errorf("expected base path %q to be a directory", basePath)
+			return "", fmt.Errorf("expected base path %q to be a directory", basePath)
 		}
 	}
+	return basePath, nil
+}
 
-	// Base parent allows getting the relative path in relation to the parent.
-	baseParent := filepath.Dir(basePath)
-
-	err = filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
+// walkSelection walks the root directory, which is either the base path or a path within it.
+// The function is called with the decision for each file and for each directory which is not walked.
+func walkSelection(basePath, root string, s *pathSelector, fn walkFunc) error {
+	w := &selectionWalker{basePath: basePath, selector: s, fn: fn}
+	if root == basePath {
+		return w.walkDir(root)
+	}
+	// The directories from the base path to the root are walked as well, so links to them form a cycle.
+	for dir := filepath.Dir(root); ; dir = filepath.Dir(dir) {
+		info, err := os.Stat(dir)
 		if err != nil {
-			return fmt.Errorf("at path %q: %w", path, err)
+			return fmt.Errorf("at path %q: %w", dir, err)
 		}
+		w.ancestors = append(w.ancestors, info)
+		if dir == basePath || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	info, err := os.Lstat(root)
+	if err != nil {
+		return fmt.Errorf("at path %q: %w", root, err)
+	}
+	return w.walkEntry(root, fs.FileInfoToDirEntry(info))
+}
 
-		// Skip directories as the filter system is built to process file paths.
-		// Skip the base path as it's already processed.
-		if d.IsDir() || path == basePath {
-			return nil
+// walkFunc is called by walkSelection with the decision for a path.
+type walkFunc func(path, relPath string, isDir bool, decision Decision) error
+
+// selectionWalker walks a directory in lexical order, selecting its paths and handling symbolic links
+// according to the options of the selector. Paths which cannot be read are skipped with a warning, unless
+// the options have no warnings collector or it is strict.
+type selectionWalker struct {
+	basePath  string
+	selector  *pathSelector
+	fn        walkFunc
+	ancestors []fs.FileInfo // Directories being walked, for detecting cycles of followed symbolic links
+}
+
+// walkDir walks the contents of a selected directory.
+func (w *selectionWalker) walkDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("at path %q: %w", path, err)
+	}
+	w.ancestors = append(w.ancestors, info)
+	defer func() { w.ancestors = w.ancestors[:len(w.ancestors)-1] }()
+
+	if err := w.selector.enterDir(path); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("at path %q: %w", path, err)
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		if err := w.walkEntry(entryPath, entry); err != nil {
+			if err := w.warn(entryPath, err); err != nil {
+				return err
+			}
 		}
+	}
+	return nil
+}
 
-		relPath, err := filepath.Rel(basePath, path)
+// warn records an error reading a path within the walked directory, which is then skipped. The error is
+// returned when it cannot be tolerated (see Warnings.Add).
+func (w *selectionWalker) warn(path string, err error) error {
+	relPath, relErr := filepath.Rel(filepath.Dir(w.basePath), path)
+	if relErr != nil {
+		return err
+	}
+	return w.selector.opts.Warnings.Add(path, filepath.ToSlash(relPath), err)
+}
+
+// walkEntry selects a path within the walked directory, walking it if it's a selected directory.
+func (w *selectionWalker) walkEntry(path string, d fs.DirEntry) error {
+	relPath, err := filepath.Rel(w.basePath, path)
+	if err != nil {
+		return fmt.Errorf("getting relative path between %q and %q: %w", w.basePath, path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	if d.Type()&fs.ModeSymlink != 0 {
+		return w.walkSymlink(path, relPath)
+	}
+	if d.IsDir() {
+		return w.walkSelectedDir(path, relPath)
+	}
+
+	decision, err := w.selector.selectFile(path, relPath, d)
+	if err != nil {
+		return err
+	}
+	return w.fn(path, relPath, false, decision)
+}
+
+// walkSelectedDir walks a directory if it's selected, or reports the decision which excluded it.
+func (w *selectionWalker) walkSelectedDir(path, relPath string) error {
+	decision, err := w.selector.selectDir(path, relPath)
+	if err != nil {
+		return err
+	} else if !decision.Included {
+		return w.fn(path, relPath, true, decision)
+	}
+	return w.walkDir(path)
+}
+
+// walkSymlink handles a symbolic link according to the symlink policy. Skipped and recorded links are
+// selected by the patterns alone, after which skipped links are excluded. Followed links are selected as the
+// file or directory they point to, excluding links to a directory being walked as they would form a cycle.
+func (w *selectionWalker) walkSymlink(path, relPath string) error {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return fmt.Errorf("at path %q: %w", path, err)
+	}
+
+	if w.selector.opts.Symlinks != SymlinksFollow {
+		decision, err := w.selector.selectPatterns(path, relPath)
 		if err != nil {
-			return fmt.Errorf("getting relative path between %q and %q: %w", basePath, path, err)
+			return err
 		}
-		relPath = filepath.ToSlash(relPath)
+		if decision.Included && w.selector.opts.Symlinks == SymlinksRecord {
+			decision.Link = target
+		} else if decision.Included {
+			decision = Decision{Pattern: decision.Pattern, Excluded: fmt.Sprintf("symbolic link to %s is not followed", target)}
+		}
+		return w.fn(path, relPath, false, decision)
+	}
 
-		if gi.MatchesPath(relPath) || !f.MatchesPath(relPath) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return w.fn(path, relPath, false, Decision{Excluded: fmt.Sprintf("broken symbolic link to %s", target)})
+	} else if err != nil {
+		return fmt.Errorf("at path %q: %w", path, err)
+	}
+	if !info.IsDir() {
+		decision, err := w.selector.selectFile(path, relPath, fs.FileInfoToDirEntry(info))
+		if err != nil {
+			return err
+		}
+		return w.fn(path, relPath, false, decision)
+	}
+	// Directories are compared by device and inode (see os.SameFile).
+	for _, ancestor := range w.ancestors {
+		if os.SameFile(ancestor, info) {
+			return w.fn(path, relPath, true, Decision{Excluded: fmt.Sprintf("symbolic link to %s forms a cycle", target)})
+		}
+	}
+	return w.walkSelectedDir(path, relPath)
+}
+
+// TraverseDirectory traverses the directory and collects path information using the filter package.
+// Files selected by the filters but skipped due to the metadata options are also returned.
+func TraverseDirectory(dir string, opts TraverseOptions) ([]PathInfo, []SkippedPath, error) {
+	paths, skipped, err := traverseFiles(dir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = processPaths(&paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paths, skipped, nil
+}
+
+// traverseFiles collects the files selected within the directory, without their parent directories
+// (see processPaths), along with the files which were skipped.
+func traverseFiles(dir string, opts TraverseOptions) ([]PathInfo, []SkippedPath, error) {
+	s, basePath, err := newPathSelector(dir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Base parent allows getting the relative path in relation to the parent.
+	baseParent := filepath.Dir(basePath)
+
+	paths := make([]PathInfo, 0)
+	skipped := make([]SkippedPath, 0)
+	err = walkSelection(basePath, basePath, s, func(path, _ string, isDir bool, decision Decision) error {
+		if isDir || (!decision.Included && decision.Skipped == "") {
 			return nil
 		}
 
-		relPath, err = filepath.Rel(baseParent, path)
+		relPath, err := filepath.Rel(baseParent, path)
 		if err != nil {
 			return fmt.Errorf("getting relative path between %q and %q: %w", basePath, path, err)
 		}
 		relPath = filepath.ToSlash(relPath)
 
+		if !decision.Included {
+			skipped = append(skipped, SkippedPath{Path: path, RelativePath: relPath, Reason: decision.Skipped})
+			return nil
+		}
+
 		paths = append(paths, PathInfo{
 			Path:         path,
 			RelativePath: relPath,
 			Depth:        strings.Count(relPath, "/") + 1,
 			IsDir:        false,
+			Class:        decision.Classification.Class,
+			Language:     decision.Language,
+			Link:         decision.Link,
 		})
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("walking directory %q: %w", basePath, err)
-	}
-
-	err = processPaths(&paths)
-	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("walking directory %q: %w", basePath, err)
 	}
-	return paths, nil
+	return paths, skipped, nil
 }
 
 // ProcessPaths adds all parent directory paths to the given slice of PathInfo.
@@ -126,7 +126,10 @@ func TestTraverseDirectories(t *testing.T) {
 			if tt.gitignorePaths == nil {
 				tt.gitignorePaths = make([]string, 0)
 			}
-			paths, err := TraverseDirectory(tt.directory, tt.filterPatterns, tt.gitignorePaths)
+			paths, _, err := TraverseDirectory(tt.directory, TraverseOptions{
+				FilterPatterns: tt.filterPatterns,
+				GitignorePaths: tt.gitignorePaths,
+			})
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -355,3 +358,249 @@ func TestProcessPaths(t *testing.T) {
 		})
 	}
 }
+
+func TestTraverseDirectoryRespectGitignore(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	// Create the test files with their contents.
+	testFiles := map[string]string{
+		"global ignore":          "*.bak\n",
+		"repo/.git/HEAD":         "ref: refs/heads/main\n",
+		"repo/.git/config":       "[core]\n\tbare = false\n\texcludesFile = \"~/global ignore\" ; comment\n",
+		"repo/.git/info/exclude": "*.secret\n",
+		"repo/.gitignore":        "*.log\nbuild/\n",
+		"repo/main.go":           "",
+		"repo/debug.log":         "",
+		"repo/notes.secret":      "",
+		"repo/old.bak":           "",
+		"repo/build/out.go":      "",
+		"repo/src/.gitignore": heredoc.Doc(`
+			!keep.log
+			/gen.go
+		`),
+		"repo/src/app.go":     "",
+		"repo/src/gen.go":     "",
+		"repo/src/keep.log":   "",
+		"repo/src/other.log":  "",
+		"repo/src/sub/gen.go": "",
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	tests := map[string]struct {
+		directory    string
+		wantRelPaths []string
+	}{
+		"repository root": {
+			directory: filepath.Join(tmpDir, "repo"),
+			wantRelPaths: []string{
+				"repo/main.go",
+				"repo/src/app.go",
+				"repo/src/keep.log",
+				"repo/src/sub/gen.go",
+			},
+		},
+		"subdirectory uses parent gitignore files": {
+			directory: filepath.Join(tmpDir, "repo", "src"),
+			wantRelPaths: []string{
+				"src/app.go",
+				"src/keep.log",
+				"src/sub/gen.go",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			paths, _, err := TraverseDirectory(tt.directory, TraverseOptions{
+				FilterPatterns:   []string{"*", "!**/.gitignore"},
+				RespectGitignore: true,
+			})
+			require.NoError(t, err)
+
+			gotPaths := make([]string, 0)
+			for _, p := range paths {
+				if !p.IsDir {
+					gotPaths = append(gotPaths, p.RelativePath)
+				}
+			}
+			assert.ElementsMatch(t, tt.wantRelPaths, gotPaths)
+		})
+	}
+}
+
+func TestTraverseDirectoryIgnoreFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Create the test files with their contents.
+	testFiles := map[string]string{
+		"project/.amalgoignore":           "*.log\nre:_gen\\.go$\n",
+		"project/.gitignore.extra":        "*.tmp\n",
+		"project/main.go":                 "",
+		"project/debug.log":               "",
+		"project/cache.tmp":               "",
+		"project/pkg/.amalgoignore":       "fixtures/\n!fixtures/small.json\n!keep.tmp\n",
+		"project/pkg/pkg.go":              "",
+		"project/pkg/types_gen.go":        "",
+		"project/pkg/keep.tmp":            "",
+		"project/pkg/fixtures/big.json":   "",
+		"project/pkg/fixtures/small.json": "",
+		"project/pkg/sub/fixtures/x.json": "",
+		"project/fixtures/root.json":      "",
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	tests := map[string]struct {
+		directory     string
+		noIgnoreFiles bool
+		wantRelPaths  []string
+	}{
+		"anchored to their directory": {
+			directory: filepath.Join(tmpDir, "project"),
+			wantRelPaths: []string{
+				"project/main.go",
+				"project/pkg/pkg.go",
+				"project/pkg/keep.tmp",
+				"project/pkg/fixtures/small.json",
+				"project/pkg/sub/fixtures/x.json",
+				"project/fixtures/root.json",
+			},
+		},
+		"disabled": {
+			directory:     filepath.Join(tmpDir, "project"),
+			noIgnoreFiles: true,
+			wantRelPaths: []string{
+				"project/main.go",
+				"project/debug.log",
+				"project/pkg/pkg.go",
+				"project/pkg/types_gen.go",
+				"project/pkg/keep.tmp",
+				"project/pkg/fixtures/big.json",
+				"project/pkg/fixtures/small.json",
+				"project/pkg/sub/fixtures/x.json",
+				"project/fixtures/root.json",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			paths, _, err := TraverseDirectory(tt.directory, TraverseOptions{
+				FilterPatterns: []string{"**", "!**/.*"},
+				GitignorePaths: []string{filepath.Join(tmpDir, "project", ".gitignore.extra")},
+				NoIgnoreFiles:  tt.noIgnoreFiles,
+			})
+			require.NoError(t, err)
+
+			gotPaths := make([]string, 0)
+			for _, p := range paths {
+				if !p.IsDir {
+					gotPaths = append(gotPaths, p.RelativePath)
+				}
+			}
+			assert.ElementsMatch(t, tt.wantRelPaths, gotPaths)
+		})
+	}
+}
+
+func TestTraverseDirectorySymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFiles := map[string]string{
+		"project/app/main.go":  "package main",
+		"shared/pkg/lib.go":    "package pkg",
+		"shared/pkg/README.md": "# pkg",
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	links := map[string]string{
+		"project/app/pkg":      "../../shared/pkg",
+		"project/app/alias.go": "main.go",
+		"project/app/loop":     "..",
+		"project/app/gone.go":  "missing.go",
+	}
+	for path, target := range links {
+		require.NoError(t, os.Symlink(target, filepath.Join(tmpDir, path)))
+	}
+
+	tests := map[string]struct {
+		policy    SymlinkPolicy
+		wantPaths map[string]string // Relative paths of the files, and the targets of recorded links
+	}{
+		"skip": {
+			policy:    SymlinksSkip,
+			wantPaths: map[string]string{"project/app/main.go": ""},
+		},
+		"follow": {
+			policy: SymlinksFollow,
+			wantPaths: map[string]string{
+				"project/app/main.go":       "",
+				"project/app/alias.go":      "",
+				"project/app/pkg/lib.go":    "",
+				"project/app/pkg/README.md": "",
+			},
+		},
+		"record": {
+			policy: SymlinksRecord,
+			wantPaths: map[string]string{
+				"project/app/main.go":  "",
+				"project/app/alias.go": "main.go",
+				"project/app/pkg":      "../../shared/pkg",
+				"project/app/loop":     "..",
+				"project/app/gone.go":  "missing.go",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			paths, _, err := TraverseDirectory(filepath.Join(tmpDir, "project"), TraverseOptions{
+				FilterPatterns: []string{"**"},
+				Symlinks:       tt.policy,
+			})
+			require.NoError(t, err)
+
+			gotPaths := make(map[string]string)
+			for _, p := range paths {
+				if !p.IsDir {
+					gotPaths[p.RelativePath] = p.Link
+				}
+			}
+			assert.Equal(t, tt.wantPaths, gotPaths)
+		})
+	}
+
+	t.Run("follow from a subdirectory", func(t *testing.T) {
+		// Links to the directories above the walked subdirectory also form a cycle.
+		explanations, err := ExplainDirectory(filepath.Join(tmpDir, "project"), TraverseOptions{
+			FilterPatterns: []string{"**"},
+			Symlinks:       SymlinksFollow,
+		}, []string{filepath.Join(tmpDir, "project", "app")})
+		require.NoError(t, err)
+
+		gotPaths := make(map[string]string)
+		for _, e := range explanations {
+			gotPaths[e.RelativePath] = e.Decision.Excluded
+		}
+		assert.Equal(t, map[string]string{
+			"project/app/main.go":       "",
+			"project/app/alias.go":      "",
+			"project/app/pkg/lib.go":    "",
+			"project/app/pkg/README.md": "",
+			"project/app/loop":          "symbolic link to .. forms a cycle",
+			"project/app/gone.go":       "broken symbolic link to missing.go",
+		}, gotPaths)
+	})
+}
@@ -45,12 +45,13 @@ func GenerateTree(paths []PathInfo) string {
 		return "< no paths found >\n"
 	}
 
+	// Paths are arranged by their relative path, so that the roots of several directories share the tree.
 	mapPathToChildren := make(map[string][]PathInfo)
 	for _, path := range paths {
 		if path.Depth == 0 {
 			continue
 		}
-		parent := filepath.Dir(path.Path)
+		parent := filepath.Dir(path.RelativePath)
 		mapPathToChildren[parent] = append(mapPathToChildren[parent], path)
 	}
 
@@ -66,6 +67,8 @@ func GenerateTree(paths []PathInfo) string {
 		name := filepath.Base(path.Path)
 		if path.IsDir {
 			name += "/"
+		} else if path.Link != "" {
+			name += " -> " + path.Link
 		}
 		output += fmt.Sprintf("%s%s%s\n", prefix, connector, name)
 
@@ -75,21 +78,14 @@ func GenerateTree(paths []PathInfo) string {
 			childPrefix = prefix + "    "
 		}
 
-		pathChildren := mapPathToChildren[path.Path]
+		pathChildren := mapPathToChildren[path.RelativePath]
 		for i, child := range pathChildren {
 			printTree(child, childPrefix, i == len(pathChildren)-1)
 		}
 	}
 
-	shortestPath := paths[0]
-	for _, path := range paths {
-		if len(path.Path) < len(shortestPath.Path) {
-			shortestPath = path
-		}
-	}
-
 	// Find and process root level items.
-	rootPaths := mapPathToChildren[filepath.Dir(shortestPath.Path)]
+	rootPaths := mapPathToChildren["."]
 	for i, path := range rootPaths {
 		printTree(path, "", i == len(rootPaths)-1)
 	}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Warning describes a path which could not be read, and was skipped instead of aborting
+type Warning struct {
+	Path         string
+	RelativePath string
+	Err          error
+}
+
+// Message describes the failed operation without repeating the path (eg. "open failed: permission denied").
+func (w Warning) Message() string {
+	var pathErr *fs.PathError
+	if errors.As(w.Err, &pathErr) {
+		return fmt.Sprintf("%s failed: %v", pathErr.Op, pathErr.Err)
+	}
+	return w.Err.Error()
+}
+
+// Warnings collects the paths which could not be read while traversing and dumping. A nil collector, like
+// a strict one, tolerates no errors.
+type Warnings struct {
+	Strict   bool
+	Warnings []Warning
+}
+
+// Add records an error accessing the filesystem at the given path, returning nil so that the path is
+// skipped. Other errors, and any error when strict, are returned. A path is only recorded once, as each
+// section of the output may fail to read it.
+func (w *Warnings) Add(path, relPath string, err error) error {
+	var pathErr *fs.PathError
+	if w == nil || w.Strict || !errors.As(err, &pathErr) {
+		return err
+	}
+	for _, warning := range w.Warnings {
+		if warning.Path == path {
+			return nil
+		}
+	}
+	w.Warnings = append(w.Warnings, Warning{Path: path, RelativePath: relPath, Err: err})
+	return nil
+}
+
+// List returns the collected warnings, which is empty for a nil collector.
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+	return w.Warnings
+}
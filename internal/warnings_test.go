@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Broderick-Westrope/amalgo/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarningsAdd(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "/tmp/secret.txt", Err: fs.ErrPermission}
+	tests := map[string]struct {
+		warnings     *Warnings
+		err          error
+		wantErr      bool
+		wantMessages []string
+	}{
+		"filesystem error": {
+			warnings:     &Warnings{},
+			err:          fmt.Errorf("reading file: %w", pathErr),
+			wantMessages: []string{"open failed: permission denied"},
+		},
+		"other error": {
+			warnings: &Warnings{},
+			err:      errors.New("parsing file"),
+			wantErr:  true,
+		},
+		"strict": {
+			warnings: &Warnings{Strict: true},
+			err:      pathErr,
+			wantErr:  true,
+		},
+		"nil collector": {
+			err:     pathErr,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.warnings.Add("/tmp/secret.txt", "tmp/secret.txt", tt.err)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Empty(t, tt.warnings.List())
+				return
+			}
+			require.NoError(t, err)
+
+			messages := make([]string, 0)
+			for _, w := range tt.warnings.List() {
+				assert.Equal(t, "tmp/secret.txt", w.RelativePath)
+				messages = append(messages, w.Message())
+			}
+			assert.Equal(t, tt.wantMessages, messages)
+		})
+	}
+}
+
+func TestTraverseDirectoryWarnings(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "project"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "project", "main.go"), nil, 0644))
+	require.NoError(t, os.Symlink("self", filepath.Join(tmpDir, "project", "self")))
+
+	opts := TraverseOptions{
+		FilterPatterns: []string{"**"},
+		Symlinks:       SymlinksFollow,
+		Warnings:       &Warnings{},
+	}
+	paths, _, err := TraverseDirectory(filepath.Join(tmpDir, "project"), opts)
+	require.NoError(t, err)
+	assert.Len(t, paths, 2)
+	require.Len(t, opts.Warnings.List(), 1)
+	assert.Equal(t, "project/self", opts.Warnings.List()[0].RelativePath)
+
+	opts.Warnings = &Warnings{Strict: true}
+	_, _, err = TraverseDirectory(filepath.Join(tmpDir, "project"), opts)
+	assert.Error(t, err)
+}
+
+func TestDumpFilesWarnings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vanished.go")
+	paths := []PathInfo{{Path: path, RelativePath: "project/vanished.go", Depth: 2}}
+
+	warnings := &Warnings{}
+	output, err := dumpFiles(paths, nil, OutputOptions{SkipBinary: true, Warnings: warnings})
+	require.NoError(t, err)
+	assert.NotContains(t, output, "vanished.go")
+	require.Len(t, warnings.List(), 1)
+	assert.Equal(t, "open failed: no such file or directory", warnings.List()[0].Message())
+
+	_, err = dumpFiles(paths, nil, OutputOptions{SkipBinary: true, Warnings: &Warnings{Strict: true}})
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestGenerateOutputWarnings(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module example.com/project\n"), 0644))
+	path := filepath.Join(tmpDir, "vanished.go")
+	paths := []PathInfo{{Path: path, RelativePath: "project/vanished.go", Depth: 2}}
+
+	tests := map[string]OutputOptions{
+		"outline":           {NoDump: true, NoTree: true, Outline: true},
+		"outline JSON":      {NoDump: true, NoTree: true, Outline: true, Format: OutputFormatJSON},
+		"references":        {NoDump: true, NoTree: true, References: true},
+		"references JSON":   {NoDump: true, NoTree: true, References: true, Format: OutputFormatJSON},
+		"dependencies":      {NoDump: true, NoTree: true, Dependencies: true},
+		"dependencies JSON": {NoDump: true, NoTree: true, Dependencies: true, Format: OutputFormatJSON},
+		"every section":     {SkipBinary: true, Outline: true, References: true, Dependencies: true},
+	}
+
+	registry := parser.NewRegistry()
+	registry.Register(parser.NewGoParser(parser.GoParserOptions{}))
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts.Warnings = &Warnings{}
+			output, err := GenerateOutput(paths, nil, registry, opts)
+			require.NoError(t, err)
+			assert.Contains(t, output, "open failed: no such file or directory")
+			assert.Len(t, opts.Warnings.List(), 1)
+
+			opts.Warnings = &Warnings{Strict: true}
+			_, err = GenerateOutput(paths, nil, registry, opts)
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+		})
+	}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"slices"
 	"strings"
 
 	"github.com/Broderick-Westrope/amalgo/internal"
 	"github.com/Broderick-Westrope/amalgo/internal/parser"
+	"github.com/Broderick-Westrope/amalgo/pkg/filter"
 	"github.com/alecthomas/kong"
 	"github.com/fatih/color"
 )
@@ -26,6 +30,7 @@ func run() int {
 	}
 
 	exitHandler := &exitWriter{code: -1}
+	resolver := &configResolver{}
 	ctx := kong.Parse(&cli,
 		kong.Name(appName),
 		kong.Description("Create consolidated snapshots of source code for analysis, documentation, and sharing with LLMs."),
@@ -33,6 +38,8 @@ func run() int {
 		kong.Writers(os.Stdout, exitHandler),
 		kong.Exit(exitHandler.Exit),
 		kong.DefaultEnvars(appName),
+		kong.Resolvers(resolver),
+		kong.Bind(resolver),
 		kong.Vars{"version": string(cli.Version)},
 	)
 
@@ -42,9 +49,12 @@ func run() int {
 	case exitHandler.code != -1:
 		fmt.Fprintf(os.Stderr, "%s", exitHandler.message)
 		return exitHandler.code
+	case resolver.err != nil:
+		fmt.Fprintf(os.Stderr, "error: %v\n", resolver.err)
+		return 1
 	}
 
-	err := ctx.Run()
+	err := ctx.Run(&cli)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		return 1
@@ -53,21 +63,49 @@ func run() int {
 }
 
 type RootCmd struct {
-	// Default command args and flags
-	Dir           string                `arg:"" optional:"" help:"Directory to analyze. If a file is provided it's parent directory will be used." type:"path" default:"."`
-	Output        string                `help:"Specifies the destination path for the output file. The file extension will automatically adjust based on the selected format (see '--format')." short:"o" type:"path" placeholder:"amalgo.txt"`
-	Stdout        bool                  `help:"Redirects all output to standard output (terminal) instead of writing to a file. Useful for piping output to other commands."`
-	Filter        []string              `help:"Controls which files are processed using glob patterns. Include patterns are processed first, then exclude patterns (prefixed with '!'). Hidden files and directories are excluded by default." short:"f" default:"*,!.*"`
-	GitIgnore     []string              `help:"Specifies .gitignore files to use for filtering. These patterns are processed before the filter patterns, taking precedence. Of the provided gitignore files, the last one will take the highest precedence." name:"gitignore" short:"g"`
-	NoTree        bool                  `help:"Skips the inclusion of the file tree in the output." default:"false"`
-	NoDump        bool                  `help:"Skips the inclusion of file contents in the output." default:"false"`
-	Outline       bool                  `help:"Includes in the output a language-aware outline of code files, showing functions, classes, and other significant elements. Only available for specific file extensions: '.go'." default:"false"`
-	NoColor       bool                  `help:"Disables ANSI color codes in the output." default:"false"`
-	IncludeBinary bool                  `help:"Processes binary files instead of skipping them. Use with caution as this may produce large or unreadable output." default:"false"`
-	Format        internal.OutputFormat `help:"Selects an alternative output format. This affects both the structure and the file extension of the output. Options: 'default', 'json'." enum:"default,json" default:"default"`
+	// Flags
+	Output           string                     `help:"Specifies the destination path for the output file. The file extension will automatically adjust based on the selected format (see '--format')." short:"o" type:"path" placeholder:"amalgo.txt"`
+	Stdout           bool                       `help:"Redirects all output to standard output (terminal) instead of writing to a file. Useful for piping output to other commands."`
+	Filter           []string                   `help:"Controls which files are processed using glob patterns. Include patterns are processed first, then exclude patterns (prefixed with '!'). Patterns are separated by commas, except within braces (eg. '*.{js,ts}') or when escaped ('\\,'). A pattern may be prefixed with its kind: 'glob:' (the default), 'path:' for a literal path, or 're:' for a regular expression. Hidden files and directories are excluded by default." short:"f" default:"*,!.*" sep:"none"`
+	GitIgnore        []string                   `help:"Specifies .gitignore files to use for filtering. These patterns are processed before the filter patterns, taking precedence. Of the provided gitignore files, the last one will take the highest precedence." name:"gitignore" short:"g"`
+	NoAmalgoignore   bool                       `help:"Disables the discovery of '.amalgoignore' files, whose patterns exclude the paths they match. They use the syntax of '--filter' anchored to their own directory, and take precedence over the '--gitignore' files and the '.amalgoignore' files above them." default:"false"`
+	RespectGitignore bool                       `help:"Excludes the files ignored by git. The .gitignore files of the repository are discovered at every level and anchored to their own directory, along with '.git/info/exclude' and the user's 'core.excludesFile'." default:"false"`
+	MaxSize          internal.ByteSize          `help:"Skips files larger than the given size (eg. '200KB' or '1MiB'). Skipped files are listed at the end of the output." placeholder:"SIZE"`
+	MinSize          internal.ByteSize          `help:"Skips files smaller than the given size (eg. '10B'). Skipped files are listed at the end of the output." placeholder:"SIZE"`
+	ModifiedSince    internal.Since             `help:"Skips files last modified before the given date (eg. '2026-09-01') or duration before now (eg. '7d' or '12h'). Skipped files are listed at the end of the output." placeholder:"TIME"`
+	MaxLines         int                        `help:"Skips files with more than the given number of lines. Skipped files are listed at the end of the output." placeholder:"LINES"`
+	Contains         []string                   `help:"Includes only the files whose contents match any of the given regular expressions (eg. 'func .*Handler'). As with grep, '^' and '$' match at the start and end of each line. This is applied after the filter patterns." sep:"none" placeholder:"REGEX"`
+	NotContains      []string                   `help:"Excludes the files whose contents match any of the given regular expressions (eg. 'Code generated .* DO NOT EDIT'). This is applied after the filter patterns." sep:"none" placeholder:"REGEX"`
+	ExcludeGenerated bool                       `help:"Excludes generated files: those with a 'Code generated ... DO NOT EDIT.' header, protobuf and gRPC outputs, minified scripts and stylesheets, and those marked 'linguist-generated' in '.gitattributes'." default:"false"`
+	ExcludeVendored  bool                       `help:"Excludes vendored files: those within 'vendor/' or 'third_party/' directories, and those marked 'linguist-vendored' in '.gitattributes'." default:"false"`
+	ExcludeLockfiles bool                       `help:"Excludes the lock files of package managers (eg. 'go.sum', 'package-lock.json', 'yarn.lock', and 'Cargo.lock')." default:"false"`
+	Summarize        bool                       `help:"Summarizes generated, vendored, and lock files with their size and number of lines instead of dumping their contents." default:"false"`
+	Lang             []string                   `help:"Includes only the files of the given languages (eg. 'go,python'). Languages are detected from the file's modeline, name, shebang line, or extension, and files of unknown languages are excluded." placeholder:"LANGUAGE"`
+	ExcludeLang      []string                   `help:"Excludes the files of the given languages (eg. 'markdown,yaml')." placeholder:"LANGUAGE"`
+	Git              gitSelectionFlag           `help:"Selects files from the git repository instead of every file of the directory: 'tracked' for the files in the index, 'staged' for those with staged changes, 'modified' for those with changes not yet staged, or 'changed-since <ref>' for those changed since the merge base of the reference (whether committed, staged, or not) along with untracked files. The other flags then apply to the selected files." placeholder:"MODE"`
+	Symlinks         internal.SymlinkPolicy     `help:"Selects how symbolic links are handled. Options: 'skip' to exclude them, 'follow' to include the files and directories they point to (excluding links which form a cycle), or 'record' to list them in the tree with their target without dumping their contents." enum:"skip,follow,record" default:"skip"`
+	NoTree           bool                       `help:"Skips the inclusion of the file tree in the output." default:"false"`
+	NoDump           bool                       `help:"Skips the inclusion of file contents in the output." default:"false"`
+	Outline          bool                       `help:"Includes in the output a language-aware outline of code files, showing functions, classes, and other significant elements. Only available for specific file extensions: '.go'." default:"false"`
+	Summaries        bool                       `help:"Adds a structural summary of each function and method to the outline: cyclomatic complexity, line count, called functions, and the use of goroutines, defer, and recover. Requires '--outline'." default:"false"`
+	References       bool                       `help:"Includes in the output an index of where each outlined symbol is referenced within the selected files. Go identifiers are resolved, while other languages are matched by name." default:"false"`
+	Deps             bool                       `help:"Includes in the output the import graph between the Go packages of the selected files, along with any import cycles and the packages with the highest fan-in. Only imports within the same module are included." default:"false"`
+	DepsDiagram      internal.DependencyDiagram `help:"Adds a diagram of the import graph to the output (see '--deps'). Options: 'none', 'dot', 'mermaid'." enum:"none,dot,mermaid" default:"none"`
+	Focus            []string                   `help:"Limits the output to the declarations of the given symbols (eg. 'store.New' or 'Store.Add'), the symbols they reference, and the symbols referencing them. Outlines are included for the files declaring them." placeholder:"SYMBOL"`
+	FocusDepth       int                        `help:"Number of references to follow from each focused symbol, in each direction (see '--focus')." default:"1"`
+	Decls            []string                   `help:"Dumps only the matching top-level declarations of code files instead of their whole contents: 'exported' for the exported API, or name globs (eg. '*Handler'). The package clause and imports are kept, and omitted lines are marked." placeholder:"PATTERN"`
+	NoColor          bool                       `help:"Disables ANSI color codes in the output." default:"false"`
+	IncludeBinary    bool                       `help:"Processes binary files instead of skipping them. Use with caution as this may produce large or unreadable output." default:"false"`
+	Strict           bool                       `help:"Aborts when a path cannot be read (eg. due to its permissions, or being removed during the run), instead of skipping it and listing it at the end of the output." default:"false"`
+	Format           internal.OutputFormat      `help:"Selects an alternative output format. This affects both the structure and the file extension of the output. Options: 'default', 'json'." enum:"default,json" default:"default"`
+	ConfigFile       string                     `name:"config" help:"Specifies the config file to use instead of discovering one. By default the nearest '.amalgo.yaml', '.amalgo.yml', or 'amalgo.toml' file is used, searching from the analyzed directory upward." type:"path" placeholder:"PATH"`
+	Profile          string                     `help:"Selects a named profile of the config file, whose settings take precedence over its top-level settings." placeholder:"NAME"`
 
 	// Subcommands
-	Version versionFlag `help:"Displays the current version of the tool and exits immediately." short:"v" name:"version"`
+	Generate GenerateCmd `cmd:"" default:"withargs" help:"Generates a snapshot of the directories and files. This is the default command."`
+	Explain  ExplainCmd  `cmd:"" help:"Explains whether each path within the directory is included, along with the filter pattern, gitignore file and line, or default which decided it."`
+	Config   ConfigCmd   `cmd:"" help:"Inspects the settings taken from the config file."`
+	Version  versionFlag `help:"Displays the current version of the tool and exits immediately." short:"v" name:"version"`
 }
 
 func (c *RootCmd) validate() bool {
@@ -80,8 +118,33 @@ func (c *RootCmd) validate() bool {
 	}
 
 	issues := make([]string, 0)
-	if c.NoDump && c.NoTree && !c.Outline {
-		issues = append(issues, "An empty output is not allowed (no dump, no tree, and no outline).")
+	if c.NoDump && c.NoTree && !c.Outline && !c.References && !c.Deps && len(c.Focus) == 0 {
+		issues = append(issues, "An empty output is not allowed (no dump, no tree, no outline, no references, and no dependencies).")
+	}
+	if len(c.Decls) > 0 && len(c.Focus) > 0 {
+		issues = append(issues, "Declaration dumping cannot be combined with '--focus'.")
+	}
+	for _, pattern := range c.Decls {
+		if _, err := path.Match(pattern, ""); err != nil {
+			issues = append(issues, fmt.Sprintf("Invalid declaration pattern %q.", pattern))
+		}
+	}
+	if c.FocusDepth < 0 {
+		issues = append(issues, "The focus depth cannot be negative.")
+	}
+	if c.MaxLines < 0 {
+		issues = append(issues, "The maximum number of lines cannot be negative.")
+	}
+	if c.MaxSize > 0 && c.MinSize > c.MaxSize {
+		issues = append(issues, "The minimum size cannot be larger than the maximum size.")
+	}
+	for _, language := range append(slices.Clone(c.Lang), c.ExcludeLang...) {
+		if _, ok := internal.NormalizeLanguage(language); !ok {
+			issues = append(issues, fmt.Sprintf("Unknown language %q.", language))
+		}
+	}
+	if c.Summaries && !c.Outline {
+		issues = append(issues, "Function summaries are part of the outline and require '--outline'.")
 	}
 
 	if len(issues) == 0 {
@@ -95,7 +158,84 @@ func (c *RootCmd) validate() bool {
 	return false
 }
 
-func (c *RootCmd) Run() error {
+// traverseOptions returns the options selecting the paths to process. Paths which cannot be read are
+// collected by the given warnings.
+func (c *RootCmd) traverseOptions(warnings *internal.Warnings) internal.TraverseOptions {
+	return internal.TraverseOptions{
+		FilterPatterns:   filter.SplitPatterns(c.Filter...),
+		GitignorePaths:   c.GitIgnore,
+		NoIgnoreFiles:    c.NoAmalgoignore,
+		RespectGitignore: c.RespectGitignore,
+		MaxSize:          c.MaxSize,
+		MinSize:          c.MinSize,
+		ModifiedSince:    c.ModifiedSince.Time,
+		MaxLines:         c.MaxLines,
+		Contains:         c.Contains,
+		NotContains:      c.NotContains,
+		ExcludeClasses:   c.excludedClasses(),
+		Languages:        normalizeLanguages(c.Lang),
+		ExcludeLanguages: normalizeLanguages(c.ExcludeLang),
+		Symlinks:         c.Symlinks,
+		Git:              internal.GitSelection(c.Git),
+		Warnings:         warnings,
+	}
+}
+
+// normalizeLanguages returns the languages with aliases (eg. 'py') replaced by their names.
+func normalizeLanguages(names []string) []string {
+	languages := make([]string, 0, len(names))
+	for _, name := range names {
+		language, _ := internal.NormalizeLanguage(name)
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// excludedClasses returns the classes of files to exclude.
+func (c *RootCmd) excludedClasses() []internal.FileClass {
+	classes := make([]internal.FileClass, 0)
+	if c.ExcludeGenerated {
+		classes = append(classes, internal.FileClassGenerated)
+	}
+	if c.ExcludeVendored {
+		classes = append(classes, internal.FileClassVendored)
+	}
+	if c.ExcludeLockfiles {
+		classes = append(classes, internal.FileClassLockfile)
+	}
+	return classes
+}
+
+type GenerateCmd struct {
+	Paths     []string `arg:"" optional:"" name:"path" help:"Directories and files to analyze, merged into one tree. Each directory is a root of the tree, while files are shown relative to the working directory. Defaults to the working directory." type:"path"`
+	FilesFrom string   `help:"Reads further paths to analyze from the given file, or from standard input if '-'. Paths are separated by newlines, or by NUL characters (eg. 'git ls-files -z')." placeholder:"FILE"`
+}
+
+// paths returns the paths to analyze, including those read from '--files-from'.
+func (g *GenerateCmd) paths() ([]string, error) {
+	paths := slices.Clone(g.Paths)
+	if g.FilesFrom != "" {
+		r := io.Reader(os.Stdin)
+		if g.FilesFrom != "-" {
+			file, err := os.Open(g.FilesFrom)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			r = file
+		}
+		listed, err := internal.ReadPathList(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", g.FilesFrom, err)
+		}
+		paths = append(paths, listed...)
+	} else if len(paths) == 0 {
+		paths = append(paths, ".")
+	}
+	return paths, nil
+}
+
+func (g *GenerateCmd) Run(c *RootCmd) error {
 	if !c.validate() {
 		return nil
 	}
@@ -106,22 +246,37 @@ func (c *RootCmd) Run() error {
 	}
 
 	registry := parser.NewRegistry()
-	registry.Register(parser.NewGoParser())
+	registry.Register(parser.NewGoParser(parser.GoParserOptions{
+		Summaries: c.Summaries,
+	}))
 
-	paths, err := internal.TraverseDirectory(c.Dir, c.Filter, c.GitIgnore)
+	targets, err := g.paths()
+	if err != nil {
+		return fmt.Errorf("reading paths: %w", err)
+	}
+	warnings := &internal.Warnings{Strict: c.Strict}
+	paths, skipped, err := internal.TraversePaths(targets, c.traverseOptions(warnings))
 	if err != nil {
 		return fmt.Errorf("traversing directories: %w", err)
 	}
 
 	outputOpts := internal.OutputOptions{
-		NoTree:     c.NoTree,
-		NoDump:     c.NoDump,
-		Outline:    c.Outline,
-		SkipBinary: !c.IncludeBinary,
-		Format:     c.Format,
+		NoTree:            c.NoTree,
+		NoDump:            c.NoDump,
+		Outline:           c.Outline,
+		References:        c.References,
+		Dependencies:      c.Deps,
+		DependencyDiagram: c.DepsDiagram,
+		Focus:             c.Focus,
+		FocusDepth:        c.FocusDepth,
+		Declarations:      c.Decls,
+		SkipBinary:        !c.IncludeBinary,
+		Summarize:         c.Summarize,
+		Format:            c.Format,
+		Warnings:          warnings,
 	}
 
-	output, err := internal.GenerateOutput(paths, registry, outputOpts)
+	output, err := internal.GenerateOutput(paths, skipped, registry, outputOpts)
 	if err != nil {
 		return fmt.Errorf("generating output: %w", err)
 	}
@@ -143,6 +298,41 @@ func (c *RootCmd) Run() error {
 	return nil
 }
 
+type ExplainCmd struct {
+	Paths []string `arg:"" optional:"" help:"Paths within the directory to explain. By default every file is explained, along with each directory which is not traversed." type:"path"`
+	Dir   string   `help:"Directory to analyze, against which the paths are selected." type:"path" default:"."`
+}
+
+func (e *ExplainCmd) Run(c *RootCmd) error {
+	warnings := &internal.Warnings{Strict: c.Strict}
+	explanations, err := internal.ExplainDirectory(e.Dir, c.traverseOptions(warnings), e.Paths)
+	if err != nil {
+		return fmt.Errorf("explaining paths: %w", err)
+	}
+
+	for _, explanation := range explanations {
+		status := "excluded"
+		colorize := color.RedString
+		if explanation.Decision.Included {
+			status = "included"
+			colorize = color.GreenString
+		}
+		if !c.NoColor {
+			status = colorize(status)
+		}
+
+		path := explanation.RelativePath
+		if explanation.IsDir {
+			path += "/"
+		}
+		fmt.Printf("%s %s (%s)\n", status, path, explanation.Reason())
+	}
+	for _, warning := range warnings.List() {
+		fmt.Fprintf(os.Stderr, "warning: skipped %s: %s\n", warning.RelativePath, warning.Message())
+	}
+	return nil
+}
+
 // Custom writer that can capture output for testing
 type exitWriter struct {
 	code    int
@@ -154,10 +344,41 @@ func (w *exitWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// Exit records the first exit code, as parsing continues after exiting (eg. after showing the help for
+// the default command).
 func (w *exitWriter) Exit(code int) {
-	w.code = code
+	if w.code == -1 {
+		w.code = code
+	}
+}
+
+// gitSelectionFlag is the selection of files from git, whose 'changed-since' mode is followed by its reference
+// (eg. '--git changed-since main').
+type gitSelectionFlag internal.GitSelection
+
+func (g *gitSelectionFlag) Decode(ctx *kong.DecodeContext) error {
+	var value string
+	if err := ctx.Scan.PopValueInto("mode", &value); err != nil {
+		return err
+	}
+	selection, err := internal.ParseGitSelection(value)
+	if err != nil {
+		return err
+	}
+	if selection.Mode == internal.GitChangedSince && selection.Ref == "" {
+		if err := ctx.Scan.PopValueInto("ref", &selection.Ref); err != nil {
+			return err
+		}
+		if selection, err = internal.ParseGitSelection(selection.Mode + "=" + selection.Ref); err != nil {
+			return err
+		}
+	}
+	*g = gitSelectionFlag(selection)
+	return nil
 }
 
+func (g gitSelectionFlag) String() string { return internal.GitSelection(g).String() }
+
 type versionFlag string
 
 func (v versionFlag) Decode(_ *kong.DecodeContext) error { return nil }
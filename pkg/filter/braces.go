@@ -0,0 +1,194 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxBraceRange is the largest number of values a numeric brace range may expand to.
+// Larger ranges are matched literally.
+const maxBraceRange = 1024
+
+// maxBraceExpansion is the largest number of patterns a pattern may expand to, as each is compiled separately.
+const maxBraceExpansion = 4096
+
+// ErrTooManyExpansions is returned for patterns whose braces expand to more than maxBraceExpansion patterns
+// (eg. "{1..1000}{1..1000}").
+var ErrTooManyExpansions = errors.New("too many brace expansions")
+
+// braceRangePattern matches the contents of a numeric brace range with an optional step (eg. "1..10..2").
+var braceRangePattern = regexp.MustCompile(`^(-?[0-9]{1,9})\.\.(-?[0-9]{1,9})(?:\.\.(-?[0-9]{1,9}))?$`)
+
+// SplitPatterns splits comma-separated lists of patterns. Commas within braces (eg. "*.{js,ts}")
+// or escaped with a backslash (eg. "a\,b") do not separate patterns. Escapes are kept so that
+// they may be interpreted by the pattern compiler.
+func SplitPatterns(lists ...string) []string {
+	patterns := make([]string, 0, len(lists))
+	for _, list := range lists {
+		start := 0
+		for i := 0; i < len(list); i++ {
+			switch list[i] {
+			case '\\':
+				i++
+			case '{':
+				if end := matchingBrace(list, i); end >= 0 {
+					i = end
+				}
+			case ',':
+				patterns = append(patterns, list[start:i])
+				start = i + 1
+			}
+		}
+		patterns = append(patterns, list[start:])
+	}
+	return patterns
+}
+
+// expandBraces expands the brace alternations within a pattern (eg. "*.{js,ts}" becomes "*.js" and
+// "*.ts"), including nested alternations and numeric ranges (eg. "{1..3}"). As in a shell, braces
+// which are escaped or which contain neither a comma nor a range are kept literally. An error is returned
+// if the pattern would expand to more than maxBraceExpansion patterns.
+func expandBraces(pattern string) ([]string, error) {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' {
+			i++
+			continue
+		}
+		if pattern[i] != '{' {
+			continue
+		}
+		end := matchingBrace(pattern, i)
+		if end < 0 {
+			continue
+		}
+
+		alternatives := splitAlternatives(pattern[i+1 : end])
+		if len(alternatives) == 1 {
+			var ok bool
+			alternatives, ok = expandBraceRange(alternatives[0])
+			if !ok {
+				continue
+			}
+		}
+
+		prefix := pattern[:i]
+		suffixes, err := expandBraces(pattern[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		// The alternatives are expanded first, so that the total is checked before building the result.
+		expansions := make([][]string, 0, len(alternatives))
+		total := 0
+		for _, alternative := range alternatives {
+			expanded, err := expandBraces(alternative)
+			if err != nil {
+				return nil, err
+			}
+			expansions = append(expansions, expanded)
+			total += len(expanded) * len(suffixes)
+			if total > maxBraceExpansion {
+				return nil, fmt.Errorf("%w: more than %d patterns", ErrTooManyExpansions, maxBraceExpansion)
+			}
+		}
+
+		result := make([]string, 0, total)
+		for _, expanded := range expansions {
+			for _, e := range expanded {
+				for _, suffix := range suffixes {
+					result = append(result, prefix+e+suffix)
+				}
+			}
+		}
+		return result, nil
+	}
+	return []string{pattern}, nil
+}
+
+// matchingBrace returns the index of the brace closing the one at the given index, or -1 if it is unclosed.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the contents of braces on the commas which are not escaped or nested.
+func splitAlternatives(s string) []string {
+	alternatives := make([]string, 0)
+	start := 0
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, s[start:])
+}
+
+// expandBraceRange expands a numeric range (eg. "1..3", "3..1" or "01..10..3"). Values are padded
+// with zeros when either bound has a leading zero. It returns false if s is not a valid range.
+func expandBraceRange(s string) ([]string, bool) {
+	match := braceRangePattern.FindStringSubmatch(s)
+	if match == nil {
+		return nil, false
+	}
+	from, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil, false
+	}
+	to, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil, false
+	}
+	step := 1
+	if match[3] != "" {
+		if step, err = strconv.Atoi(match[3]); err != nil || step == 0 {
+			return nil, false
+		}
+		step = max(step, -step)
+	}
+	if from > to {
+		step = -step
+	}
+	if (to-from)/step+1 > maxBraceRange {
+		return nil, false
+	}
+
+	width := 0
+	for _, bound := range match[1:3] {
+		digits := strings.TrimPrefix(bound, "-")
+		if len(digits) > 1 && digits[0] == '0' {
+			width = max(width, len(bound))
+		}
+	}
+
+	values := make([]string, 0)
+	for n := from; (step > 0 && n <= to) || (step < 0 && n >= to); n += step {
+		values = append(values, fmt.Sprintf("%0*d", width, n))
+	}
+	return values, true
+}
@@ -4,10 +4,13 @@
 package filter
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 // Filter wraps a list of filter patterns.
@@ -19,8 +22,52 @@ type Filter struct {
 type Pattern struct {
 	Pattern *regexp.Regexp
 	Negate  bool
+	DirOnly bool // Whether the pattern only matches directories (see MatchesDirHow)
 	LineNo  int
 	Line    string
+	Source  string // Path of the file the pattern was read from, if any
+
+	// ancestors matches the directories which may contain a path matched by the pattern.
+	// If nil, any directory may.
+	ancestors *regexp.Regexp
+	// descendants is whether matching a path implies matching everything within it.
+	descendants bool
+	// base is the directory the pattern is anchored to (see CompileFilterPatternFileAt). Paths
+	// outside of it are not matched. If empty, the pattern is anchored to the base directory.
+	base string
+}
+
+// relative returns the path relative to the directory the pattern is anchored to, and whether it is within it.
+func (p *Pattern) relative(path string) (string, bool) {
+	if p.base == "" {
+		return path, true
+	}
+	return strings.CutPrefix(path, p.base+"/")
+}
+
+// matches reports whether the pattern matches the path.
+func (p *Pattern) matches(path string) bool {
+	path, ok := p.relative(path)
+	return ok && p.Pattern.MatchString(path)
+}
+
+// matchesAllWithin reports whether the pattern matches every path within the directory.
+func (p *Pattern) matchesAllWithin(dir string) bool {
+	dir, ok := p.relative(dir)
+	return ok && p.descendants && !p.DirOnly && (p.Pattern.MatchString(dir) || p.Pattern.MatchString(dir+"/"))
+}
+
+// mayMatchWithin reports whether the pattern may match any path within the directory.
+func (p *Pattern) mayMatchWithin(dir string) bool {
+	// The directory the pattern is anchored to may be within the directory.
+	if p.base != "" && (dir == p.base || strings.HasPrefix(p.base, dir+"/")) {
+		return true
+	}
+	rel, ok := p.relative(dir)
+	if !ok {
+		return false
+	}
+	return p.ancestors == nil || p.ancestors.MatchString(rel) || p.matchesAllWithin(dir)
 }
 
 // MergeWithoutPrecedence prepends the patterns of the given filter.
@@ -57,6 +104,16 @@ func (f *Filter) MatchesPath(path string) bool {
 
 // MatchesPathHow returns whether the path matches and which pattern matched it.
 func (f *Filter) MatchesPathHow(path string) (bool, *Pattern) {
+	return f.matchesHow(path, false)
+}
+
+// MatchesDirHow returns whether the directory path matches and which pattern matched it.
+// Unlike MatchesPathHow, directory-only patterns from gitignore files (eg. "build/") are included.
+func (f *Filter) MatchesDirHow(path string) (bool, *Pattern) {
+	return f.matchesHow(path, true)
+}
+
+func (f *Filter) matchesHow(path string, isDir bool) (bool, *Pattern) {
 	// Normalize path separators.
 	path = filepath.ToSlash(path)
 
@@ -64,7 +121,10 @@ func (f *Filter) MatchesPathHow(path string) (bool, *Pattern) {
 	matchesPath := false
 
 	for _, pattern := range f.patterns {
-		if pattern.Pattern.MatchString(path) {
+		if pattern.DirOnly && !isDir {
+			continue
+		}
+		if pattern.matches(path) {
 			if !pattern.Negate {
 				matchesPath = true
 				matchingPattern = pattern
@@ -78,31 +138,142 @@ func (f *Filter) MatchesPathHow(path string) (bool, *Pattern) {
 	return matchesPath, matchingPattern
 }
 
+// CouldMatchWithin reports whether any file within the directory could match the patterns. When it
+// returns false, the directory may be skipped as none of its descendants will match. The result
+// is conservative, meaning it may return true for directories without any matching descendants.
+func (f *Filter) CouldMatchWithin(dir string) bool {
+	possible, _ := f.CouldMatchWithinHow(dir)
+	return possible
+}
+
+// CouldMatchWithinHow returns whether any file within the directory could match and which pattern
+// decided it, which is nil when no pattern may match within the directory.
+func (f *Filter) CouldMatchWithinHow(dir string) (bool, *Pattern) {
+	dir = strings.TrimSuffix(filepath.ToSlash(dir), "/")
+	var decidingPattern *Pattern
+	possible := false
+	for _, pattern := range f.patterns {
+		if pattern.DirOnly {
+			continue
+		}
+		if !pattern.Negate && pattern.mayMatchWithin(dir) {
+			possible = true
+			decidingPattern = pattern
+		} else if pattern.Negate && pattern.matchesAllWithin(dir) && possible {
+			// Anything matched so far within the directory has been negated.
+			possible = false
+			decidingPattern = pattern
+		}
+	}
+	return possible, decidingPattern
+}
+
+// MatchesAllWithin reports whether every file within the directory matches the patterns. As with
+// CouldMatchWithin, the result is conservative, meaning it may return false when all files match.
+func (f *Filter) MatchesAllWithin(dir string) bool {
+	all, _ := f.MatchesAllWithinHow(dir)
+	return all
+}
+
+// MatchesAllWithinHow returns whether every file within the directory matches and which pattern decided it.
+func (f *Filter) MatchesAllWithinHow(dir string) (bool, *Pattern) {
+	dir = strings.TrimSuffix(filepath.ToSlash(dir), "/")
+	var decidingPattern *Pattern
+	all := false
+	for _, pattern := range f.patterns {
+		if pattern.DirOnly {
+			continue
+		}
+		if !pattern.Negate && pattern.matchesAllWithin(dir) {
+			all = true
+			decidingPattern = pattern
+		} else if pattern.Negate && pattern.mayMatchWithin(dir) && all {
+			all = false
+			decidingPattern = pattern
+		}
+	}
+	return all, decidingPattern
+}
+
 // CompileFilterPatterns accepts a variadic set of strings and returns a Filterer
-// instance with the compiled patterns.
-func CompileFilterPatterns(patterns ...string) *Filter {
+// instance with the compiled patterns. Brace alternations (eg. "*.{js,ts}") are expanded.
+// An error is returned for each pattern which cannot be compiled (eg. "[a-z").
+func CompileFilterPatterns(patterns ...string) (*Filter, error) {
+	return compilePatterns(patterns, "", true, true, getPatternFromLine)
+}
+
+// compilePatterns compiles pattern lines, optionally expanding brace alternations. Each alternation
+// is compiled as a separate pattern, all of which retain the original line. Leading whitespace is
+// optionally trimmed, as it is significant within ignore files (eg. "  foo" only matches "  foo").
+func compilePatterns(patterns []string, source string, expand, trimLeading bool, compile func(line string) (*Pattern, error)) (*Filter, error) {
 	f := new(Filter)
+	var errs []error
 	for i, pattern := range patterns {
 		pattern = strings.TrimRight(pattern, "\r")
-		pattern = strings.TrimSpace(pattern)
-		compiledPattern, isNegated := getPatternFromLine(pattern)
-		if compiledPattern != nil {
-			fp := &Pattern{compiledPattern, isNegated, i + 1, pattern}
-			f.patterns = append(f.patterns, fp)
+		if trimLeading {
+			pattern = strings.TrimLeftFunc(pattern, unicode.IsSpace)
+		}
+		pattern = trimTrailingSpaces(pattern)
+
+		expanded := []string{pattern}
+		if expand && expandsBraces(pattern) {
+			var err error
+			expanded, err = expandBraces(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pattern %d (%q): %w", i+1, pattern, err))
+				continue
+			}
+		}
+		for _, line := range expanded {
+			fp, err := compile(line)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pattern %d (%q): %w", i+1, pattern, err))
+				break
+			}
+			if fp != nil {
+				fp.LineNo = i + 1
+				fp.Line = pattern
+				fp.Source = source
+				f.patterns = append(f.patterns, fp)
+			}
 		}
 	}
-	return f
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return f, nil
 }
 
 // CompileFilterPatternFile reads patterns from a file and compiles them.
+// As with gitignore files, braces are matched literally.
 func CompileFilterPatternFile(path string) (*Filter, error) {
+	return compilePatternFile(path, true)
+}
+
+func compilePatternFile(path string, trimLeading bool) (*Filter, error) {
 	bs, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
 	patterns := strings.Split(string(bs), "\n")
-	return CompileFilterPatterns(patterns...), nil
+	return compilePatterns(patterns, path, false, trimLeading, getPatternFromLine)
+}
+
+// CompileFilterPatternFileAt reads and compiles a pattern file which lives in the given base directory
+// (relative to the directory paths are matched against, or "" for the directory itself). The patterns
+// are anchored to the base directory, so that they only match paths within it. As with gitignore files,
+// leading whitespace is part of the patterns.
+func CompileFilterPatternFileAt(path, base string) (*Filter, error) {
+	f, err := compilePatternFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	base = strings.Trim(filepath.ToSlash(base), "/")
+	for _, pattern := range f.patterns {
+		pattern.base = base
+	}
+	return f, nil
 }
 
 // CompileExcludePatternFileAndLines compiles patterns from both a file and additional lines.
@@ -113,78 +284,189 @@ func CompileFilterPatternFileAndLines(path string, lines ...string) (*Filter, er
 	}
 
 	patterns := append(strings.Split(string(bs), "\n"), lines...)
-	return CompileFilterPatterns(patterns...), nil
+	return compilePatterns(patterns, path, false, true, getPatternFromLine)
 }
 
-// getPatternFromLine converts a single pattern line into a regexp and bool indicating
-// if it's a negated pattern. The rules follow .gitignore syntax.
-func getPatternFromLine(line string) (*regexp.Regexp, bool) {
-	// Strip comments.
-	if strings.HasPrefix(line, "#") {
-		return nil, false
+// trimTrailingSpaces removes trailing whitespace from a pattern line, unless it is escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	end := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ', '\t':
+		case '\\':
+			i++
+			end = min(i+1, len(line))
+		default:
+			end = i + 1
+		}
 	}
+	return line[:end]
+}
 
-	// Skip empty lines.
-	if line == "" {
-		return nil, false
-	}
+// Prefixes declaring the kind of a filter pattern. Patterns without a prefix are globs.
+const (
+	kindGlob   = "glob:"
+	kindPath   = "path:"
+	kindRegexp = "re:"
+)
 
-	// Check for negation prefix. Several will negate the previous negation (ie. toggling).
-	negatePattern := false
-	for line[0] == '!' {
-		negatePattern = !negatePattern
-		line = line[1:]
+// ErrEmptyPattern is returned for patterns with a kind but nothing to match (eg. "re:").
+var ErrEmptyPattern = errors.New("empty pattern")
+
+// getPatternFromLine converts a single pattern line into a pattern, or nil if the line is
+// empty or a comment. The rules follow .gitignore syntax, with globs matched in the same
+// manner as git's wildmatch, except that all patterns are anchored to the base directory.
+// The kind of pattern may be declared with a prefix: "glob:" (the default), "path:" for a
+// literal path and everything within it (where only "\," is an escape), or "re:" for an
+// unanchored regular expression which is matched against the path relative to the base
+// directory (eg. "re:_gen[0-9]+\.go$").
+func getPatternFromLine(line string) (*Pattern, error) {
+	line, negatePattern, ok := cutPatternPrefix(line)
+	if !ok {
+		return nil, nil
 	}
 
-	// Create a copy of the line to modify. The original is maintained for later checks.
-	expr := line
+	switch {
+	case strings.HasPrefix(line, kindRegexp):
+		expr := strings.TrimPrefix(line, kindRegexp)
+		if expr == "" {
+			return nil, ErrEmptyPattern
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Pattern{Pattern: pattern, Negate: negatePattern}, nil
+
+	case strings.HasPrefix(line, kindPath):
+		path := strings.Trim(strings.TrimPrefix(line, kindPath), "/")
+		if path == "" {
+			return nil, ErrEmptyPattern
+		}
+		// Commas are escaped to keep them from separating patterns (see SplitPatterns), as within globs.
+		line = escapeGlob(strings.ReplaceAll(path, `\,`, ","))
 
-	// Ignore a prefix of escaped '#' or '!'.
-	if regexp.MustCompile(`^(\#|\!)`).MatchString(expr) {
-		expr = expr[1:]
+	case strings.HasPrefix(line, kindGlob):
+		line = strings.TrimPrefix(line, kindGlob)
+		if line == "" {
+			return nil, ErrEmptyPattern
+		}
 	}
 
-	// Escape dots.
-	expr = regexp.MustCompile(`\.`).ReplaceAllString(expr, `\.`)
-
-	// This 'magic star" is used temporarily when handling other single-star cases.
-	magicStar := "#$~"
+	// Patterns ending with a slash match everything within the directory.
+	glob, isDir := strings.CutSuffix(line, "/")
 
-	// Handle '/**/' patterns.
-	if strings.HasPrefix(expr, "/**/") {
-		expr = expr[1:]
+	// Patterns starting with a slash are anchored to the base directory, as are all others.
+	prefix := "^"
+	if strings.HasPrefix(glob, "/") {
+		prefix = "^/?"
+		glob = glob[1:]
 	}
-	expr = regexp.MustCompile(`/\*\*/`).ReplaceAllString(expr, `(/|/.+/)`)
-	expr = regexp.MustCompile(`\*\*/`).ReplaceAllString(expr, `(|.`+magicStar+`/)`)
-	expr = regexp.MustCompile(`/\*\*`).ReplaceAllString(expr, `(|/.`+magicStar+`)`)
 
-	// Handle wildcards.
-	expr = regexp.MustCompile(`\\\*`).ReplaceAllString(expr, `\`+magicStar)
-	expr = regexp.MustCompile(`\*`).ReplaceAllString(expr, `([^/]*)`) // '*' may be any number of characters other than '/'
-	expr = strings.Replace(expr, "?", `[^/]`, -1)                     // '?' may be any single character other than '/'
-	expr = strings.Replace(expr, magicStar, "*", -1)
+	expr, err := wildmatchToRegexp(glob)
+	if err != nil {
+		return nil, err
+	}
 
-	// Build final regex.
-	if strings.HasSuffix(line, "/") {
-		expr += "(|.*)$"
+	// Build final regex. Matching a directory also matches everything within it.
+	if isDir {
+		expr += "/.*$"
 	} else {
 		expr += "(|/.*)$"
 	}
 
-	// Only add directory prefix for patterns starting with /
-	switch {
-	case strings.HasPrefix(line, "/"):
-		expr = "^(|/)" + expr[1:]
+	pattern, err := regexp.Compile("(?s)" + prefix + expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{
+		Pattern:     pattern,
+		Negate:      negatePattern,
+		ancestors:   ancestorsRegexp(glob),
+		descendants: true,
+	}, nil
+}
 
-	case strings.HasPrefix(line, "**/"):
-		// Pattern contains a slash but doesn't start with one
-		expr = "^(|.*/)" + expr
+// escapeGlob escapes the characters of a path which have a special meaning within globs.
+func escapeGlob(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`\*?[`, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// expandsBraces reports whether brace alternations within the pattern should be expanded, which
+// is only the case for globs.
+func expandsBraces(pattern string) bool {
+	pattern = strings.TrimLeft(pattern, "!")
+	return !strings.HasPrefix(pattern, kindRegexp) && !strings.HasPrefix(pattern, kindPath)
+}
 
-	default:
-		// Simple pattern like *.go - should only match in current directory
-		expr = "^" + expr
+// ancestorsRegexp builds a regexp matching the directories which may contain a path matched by the
+// glob (eg. "src" and "src/api" for "src/api/*.go"). A directory component of "**" may contain any
+// path. It returns nil if the components of the glob cannot be translated separately.
+func ancestorsRegexp(glob string) *regexp.Regexp {
+	components := make([]string, 0)
+	start := 0
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '/':
+			components = append(components, glob[start:i])
+			start = i + 1
+		}
 	}
+	components = append(components, glob[start:])
 
-	pattern, _ := regexp.Compile(expr)
-	return pattern, negatePattern
+	var sb strings.Builder
+	open := 0
+	for i, component := range components {
+		if i > 0 {
+			sb.WriteString("(?:/")
+			open++
+		}
+		if len(component) >= 2 && strings.Trim(component, "*") == "" {
+			sb.WriteString(".*")
+			break
+		}
+		expr, err := wildmatchToRegexp(component)
+		if err != nil {
+			return nil
+		}
+		sb.WriteString(expr)
+	}
+	sb.WriteString(strings.Repeat(")?", open))
+
+	ancestors, err := regexp.Compile("(?s)^(?:" + sb.String() + ")$")
+	if err != nil {
+		return nil
+	}
+	return ancestors
+}
+
+// cutPatternPrefix removes any negation prefix from a pattern line, reporting whether the pattern
+// is negated. It returns false if the line is empty or a comment.
+func cutPatternPrefix(line string) (string, bool, bool) {
+	// Strip comments.
+	if strings.HasPrefix(line, "#") {
+		return "", false, false
+	}
+
+	// Check for negation prefix. Several will negate the previous negation (ie. toggling).
+	negate := false
+	for line != "" && line[0] == '!' {
+		negate = !negate
+		line = line[1:]
+	}
+
+	// Skip empty lines.
+	if line == "" {
+		return "", false, false
+	}
+	return line, negate, true
 }
@@ -1,10 +1,13 @@
 package filter
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMatchesPath(t *testing.T) {
@@ -120,7 +123,8 @@ func TestMatchesPath(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			f := CompileFilterPatterns(tc.patterns...)
+			f, err := CompileFilterPatterns(tc.patterns...)
+			require.NoError(t, err)
 			got := f.MatchesPath(tc.path)
 			assert.Equal(t, got, tc.want, "path: %q, patterns: %v", tc.path, tc.patterns)
 		})
@@ -160,7 +164,8 @@ func TestMatchesPathHow(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			f := CompileFilterPatterns(tc.patterns...)
+			f, err := CompileFilterPatterns(tc.patterns...)
+			require.NoError(t, err)
 			gotMatch, gotPattern := f.MatchesPathHow(tc.path)
 
 			assert.Equal(t, tc.wantMatch, gotMatch)
@@ -336,6 +341,69 @@ func TestCompileAndMatchPatterns(t *testing.T) {
 				"test/important.txt": false,
 			},
 		},
+		"match with brace alternations": {
+			patterns: []string{"*.{js,ts}", "src/{a,b{1..2}}/*.go", "!*.{test,spec}.{js,ts}"},
+			pathsToWant: map[string]bool{
+				"main.js":       true,
+				"main.ts":       true,
+				"src/a/main.go": true,
+				"src/b2/lib.go": true,
+
+				"main.go":          false,
+				"main.{js,ts}":     false,
+				"main.test.js":     false,
+				"main.spec.ts":     false,
+				"src/b/main.go":    false,
+				"src/b3/main.go":   false,
+				"src/b{1..2}/x.go": false,
+			},
+		},
+		"match with bracket expressions": {
+			patterns: []string{"file[0-9].txt", "log[!a-z].txt", "data[^0-9].csv", "[[:upper:]]*.md"},
+			pathsToWant: map[string]bool{
+				"file1.txt":  true,
+				"log_.txt":   true,
+				"dataA.csv":  true,
+				"README.md":  true,
+				"Notes.md":   true,
+				"file12.txt": false,
+				"fileA.txt":  false,
+				"logb.txt":   false,
+				"data1.csv":  false,
+				"readme.md":  false,
+			},
+		},
+		"match regexp metacharacters literally": {
+			patterns: []string{"a+b.txt", "(x)|y.txt", "^$.txt", "c{1}.txt"},
+			pathsToWant: map[string]bool{
+				"a+b.txt":   true,
+				"(x)|y.txt": true,
+				"^$.txt":    true,
+				"c{1}.txt":  true,
+				"aab.txt":   false,
+				"x.txt":     false,
+				"y.txt":     false,
+				".txt":      false,
+				"c.txt":     false,
+			},
+		},
+		"match with escaped trailing spaces": {
+			patterns: []string{`trailing\ `, "ignored   "},
+			pathsToWant: map[string]bool{
+				"trailing ": true,
+				"ignored":   true,
+				"trailing":  false,
+				"ignored ":  false,
+			},
+		},
+		"match question mark wildcards within a directory": {
+			patterns: []string{"src/?.go", "!src/[/].go"},
+			pathsToWant: map[string]bool{
+				"src/a.go":  true,
+				"src/ab.go": false,
+				"src//.go":  false,
+			},
+		},
 		"match with question mark wildcards": {
 			patterns: []string{"test?.txt", "lib/????.go"},
 			pathsToWant: map[string]bool{
@@ -349,11 +417,80 @@ func TestCompileAndMatchPatterns(t *testing.T) {
 				"lib/utils.go": false,
 			},
 		},
+		"regular expression kind": {
+			patterns: []string{"**/*.go", `!re:_gen[0-9]+\.go$`},
+			pathsToWant: map[string]bool{
+				"main.go":                 true,
+				"internal/model_gen.go":   true,
+				"internal/model_gen1.go":  false,
+				"internal/model_gen42.go": false,
+			},
+		},
+		"regular expression kind is unanchored": {
+			patterns: []string{"re:test"},
+			pathsToWant: map[string]bool{
+				"internal/test.txt": true,
+				"testdata/a.txt":    true,
+				"main.go":           false,
+			},
+		},
+		"regular expression braces are not expanded": {
+			patterns: []string{`re:^v[0-9]{1,2}/`},
+			pathsToWant: map[string]bool{
+				"v1/main.go":   true,
+				"v10/main.go":  true,
+				"v100/main.go": false,
+			},
+		},
+		"path kind matches literally": {
+			patterns: []string{"path:cmd/server", "path:docs/[draft]*.md"},
+			pathsToWant: map[string]bool{
+				"cmd/server":          true,
+				"cmd/server/main.go":  true,
+				"cmd/server2/main.go": false,
+				"docs/[draft]*.md":    true,
+				"docs/d.md":           false,
+			},
+		},
+		"path kind with an escaped comma": {
+			patterns: []string{`path:data\,v1.csv`},
+			pathsToWant: map[string]bool{
+				"data,v1.csv":  true,
+				`data\,v1.csv`: false,
+				"data":         false,
+			},
+		},
+		"negated path kind": {
+			patterns: []string{"*", "!path:/vendor/"},
+			pathsToWant: map[string]bool{
+				"main.go":         true,
+				"vendor/lib.go":   false,
+				"vendors/lib.go":  true,
+				"src/vendor/a.go": true,
+			},
+		},
+		"glob kind": {
+			patterns: []string{"glob:**/*.go", "!glob:re:*"},
+			pathsToWant: map[string]bool{
+				"main.go":     true,
+				"src/main.go": true,
+				"re:main.go":  false,
+				"README.md":   false,
+			},
+		},
+		"unknown kind is a glob": {
+			patterns: []string{"file:*.txt"},
+			pathsToWant: map[string]bool{
+				"file:notes.txt": true,
+				"notes.txt":      false,
+			},
+		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			f := CompileFilterPatterns(tt.patterns...)
+			f, err := CompileFilterPatterns(tt.patterns...)
+			require.NoError(t, err)
 
 			for path, want := range tt.pathsToWant {
 				got := f.MatchesPath(path)
@@ -362,3 +499,389 @@ func TestCompileAndMatchPatterns(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandBraces(t *testing.T) {
+	tests := map[string]struct {
+		pattern string
+		want    []string
+		wantLen int
+		wantErr error
+	}{
+		"no braces": {
+			pattern: "*.go",
+			want:    []string{"*.go"},
+		},
+		"alternation": {
+			pattern: "*.{js,ts}",
+			want:    []string{"*.js", "*.ts"},
+		},
+		"multiple alternations": {
+			pattern: "{src,lib}/*.{js,ts}",
+			want:    []string{"src/*.js", "src/*.ts", "lib/*.js", "lib/*.ts"},
+		},
+		"nested alternation": {
+			pattern: "{a,b{c,d}}.txt",
+			want:    []string{"a.txt", "bc.txt", "bd.txt"},
+		},
+		"empty alternative": {
+			pattern: "file{,.bak}",
+			want:    []string{"file", "file.bak"},
+		},
+		"numeric range": {
+			pattern: "log{1..3}.txt",
+			want:    []string{"log1.txt", "log2.txt", "log3.txt"},
+		},
+		"descending range": {
+			pattern: "{3..1}",
+			want:    []string{"3", "2", "1"},
+		},
+		"padded range with step": {
+			pattern: "{01..10..3}",
+			want:    []string{"01", "04", "07", "10"},
+		},
+		"range within alternation": {
+			pattern: "{a,{1..2}}",
+			want:    []string{"a", "1", "2"},
+		},
+		"single item is literal": {
+			pattern: "{a}.txt",
+			want:    []string{"{a}.txt"},
+		},
+		"unclosed brace is literal": {
+			pattern: "{a,b",
+			want:    []string{"{a,b"},
+		},
+		"escaped brace is literal": {
+			pattern: `\{a,b}`,
+			want:    []string{`\{a,b}`},
+		},
+		"escaped comma": {
+			pattern: `{a\,b,c}`,
+			want:    []string{`a\,b`, "c"},
+		},
+		"literal braces around alternation": {
+			pattern: "{x{a,b}}",
+			want:    []string{"{xa}", "{xb}"},
+		},
+		"expansions within limit": {
+			pattern: "{1..64}{1..64}",
+			wantLen: 64 * 64,
+		},
+		"too many expansions": {
+			pattern: "{1..1000}{1..1000}",
+			wantErr: ErrTooManyExpansions,
+		},
+		"too many nested expansions": {
+			pattern: "{a,{1..100}{1..100}}",
+			wantErr: ErrTooManyExpansions,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := expandBraces(tc.pattern)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tc.wantLen > 0 {
+				assert.Len(t, got, tc.wantLen)
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSplitPatterns(t *testing.T) {
+	tests := map[string]struct {
+		lists []string
+		want  []string
+	}{
+		"single pattern": {
+			lists: []string{"*.go"},
+			want:  []string{"*.go"},
+		},
+		"comma separated": {
+			lists: []string{"*,!.*"},
+			want:  []string{"*", "!.*"},
+		},
+		"commas within braces": {
+			lists: []string{"*.go,*.{js,ts},{a,{b,c}}/"},
+			want:  []string{"*.go", "*.{js,ts}", "{a,{b,c}}/"},
+		},
+		"escaped comma": {
+			lists: []string{`a\,b.txt,c.txt`},
+			want:  []string{`a\,b.txt`, "c.txt"},
+		},
+		"unclosed brace": {
+			lists: []string{"{a,b"},
+			want:  []string{"{a", "b"},
+		},
+		"repeated flags": {
+			lists: []string{"*.go", "*.{js,ts}"},
+			want:  []string{"*.go", "*.{js,ts}"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, SplitPatterns(tc.lists...))
+		})
+	}
+}
+
+func TestCompileFilterPatternsErrors(t *testing.T) {
+	tests := map[string]struct {
+		patterns []string
+		wantErr  error
+	}{
+		"unclosed bracket expression": {
+			patterns: []string{"*.go", "file[0-9.txt"},
+			wantErr:  ErrUnclosedBracket,
+		},
+		"trailing backslash": {
+			patterns: []string{`dir\`},
+			wantErr:  ErrTrailingBackslash,
+		},
+		"invalid character class": {
+			patterns: []string{"[[:spaci:]]"},
+			wantErr:  ErrInvalidClass,
+		},
+		"invalid pattern within brace alternation": {
+			patterns: []string{"*.{go,[}"},
+			wantErr:  ErrUnclosedBracket,
+		},
+		"empty regular expression": {
+			patterns: []string{"!re:"},
+			wantErr:  ErrEmptyPattern,
+		},
+		"empty path": {
+			patterns: []string{"path:/"},
+			wantErr:  ErrEmptyPattern,
+		},
+		"too many brace expansions": {
+			patterns: []string{"*.go", "{1..1000}{1..1000}"},
+			wantErr:  ErrTooManyExpansions,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := CompileFilterPatterns(tc.patterns...)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Nil(t, f)
+		})
+	}
+}
+
+func TestCouldMatchWithin(t *testing.T) {
+	tests := map[string]struct {
+		patterns []string
+		dir      string
+		want     bool
+	}{
+		"wildcard matches top-level directory": {
+			patterns: []string{"*"},
+			dir:      "src",
+			want:     true,
+		},
+		"excluded directory": {
+			patterns: []string{"*", "!node_modules/"},
+			dir:      "node_modules",
+			want:     false,
+		},
+		"excluded hidden directory": {
+			patterns: []string{"*", "!.*"},
+			dir:      ".git",
+			want:     false,
+		},
+		"excluded directory with later re-inclusion": {
+			patterns: []string{"*", "!vendor/", "vendor/keep/*.go"},
+			dir:      "vendor",
+			want:     true,
+		},
+		"re-inclusion in another directory": {
+			patterns: []string{"*", "!vendor/", "src/*.go"},
+			dir:      "vendor",
+			want:     false,
+		},
+		"ancestor of anchored pattern": {
+			patterns: []string{"src/api/*.go"},
+			dir:      "src",
+			want:     true,
+		},
+		"sibling of anchored pattern": {
+			patterns: []string{"src/api/*.go"},
+			dir:      "src/web",
+			want:     false,
+		},
+		"descendant of matching directory": {
+			patterns: []string{"src/"},
+			dir:      "src/deep/nested",
+			want:     true,
+		},
+		"double asterisk matches any directory": {
+			patterns: []string{"**/*.go"},
+			dir:      "a/b/c",
+			want:     true,
+		},
+		"double asterisk after directory": {
+			patterns: []string{"src/**/*.go"},
+			dir:      "srcs",
+			want:     false,
+		},
+		"negation of some files": {
+			patterns: []string{"*", "!*.md"},
+			dir:      "docs",
+			want:     true,
+		},
+		"no patterns": {
+			patterns: []string{},
+			dir:      "src",
+			want:     false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := CompileFilterPatterns(tt.patterns...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.CouldMatchWithin(tt.dir))
+		})
+	}
+}
+
+func TestMatchesAllWithin(t *testing.T) {
+	tests := map[string]struct {
+		patterns []string
+		dir      string
+		want     bool
+	}{
+		"matching directory": {
+			patterns: []string{"build/"},
+			dir:      "build",
+			want:     true,
+		},
+		"descendant of matching directory": {
+			patterns: []string{"build"},
+			dir:      "build/out",
+			want:     true,
+		},
+		"negation within directory": {
+			patterns: []string{"build/", "!build/keep.txt"},
+			dir:      "build",
+			want:     false,
+		},
+		"negation elsewhere": {
+			patterns: []string{"build/", "!src/keep.txt"},
+			dir:      "build",
+			want:     true,
+		},
+		"only some files": {
+			patterns: []string{"build/*.o"},
+			dir:      "build",
+			want:     false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := CompileFilterPatterns(tt.patterns...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.MatchesAllWithin(tt.dir))
+		})
+	}
+}
+
+func TestWithinAgreesWithMatchesPath(t *testing.T) {
+	patterns := [][]string{
+		{"*", "!.*"},
+		{"*", "!vendor/", "vendor/keep/*.go"},
+		{"src/**/*.go", "!src/gen/"},
+		{"**/*.md", "!docs/"},
+		{"a/*/c/*.txt"},
+		{"[ab]*/x?/**"},
+	}
+	dirs := []string{"src", "src/gen", "src/api", "vendor", "vendor/keep", "docs", "a", "a/b", "a/b/c", "b/xy", ".git", "bx"}
+	files := []string{"main.go", "x.go", "README.md", "note.txt", "keep/x.go", "c/y.txt", "deep/nested/z.go"}
+
+	for _, p := range patterns {
+		f, err := CompileFilterPatterns(p...)
+		require.NoError(t, err)
+		for _, dir := range dirs {
+			anyMatch, allMatch := false, true
+			for _, file := range files {
+				matches := f.MatchesPath(dir + "/" + file)
+				anyMatch = anyMatch || matches
+				allMatch = allMatch && matches
+			}
+			if !f.CouldMatchWithin(dir) {
+				assert.False(t, anyMatch, "patterns %q match a path within %q", p, dir)
+			}
+			if f.MatchesAllWithin(dir) {
+				assert.True(t, allMatch, "patterns %q do not match every path within %q", p, dir)
+			}
+		}
+	}
+}
+
+func TestCompileFilterPatternFileAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".amalgoignore")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		"*.json",
+		"fixtures/",
+		"!fixtures/keep.json",
+		"/root.txt",
+		"path:docs/a[1].md",
+		"re:_gen\\.go$",
+	}, "\n")), 0644))
+
+	f, err := CompileFilterPatternFileAt(path, "src/")
+	require.NoError(t, err)
+
+	matchTests := map[string]bool{
+		"src/data.json":             true,
+		"src/api/data.json":         false,
+		"data.json":                 false,
+		"other/src/data.json":       false,
+		"src/fixtures/a.txt":        true,
+		"src/fixtures/keep.json":    false,
+		"fixtures/a.txt":            false,
+		"src/root.txt":              true,
+		"root.txt":                  false,
+		"src/docs/a[1].md":          true,
+		"docs/a[1].md":              false,
+		"src/api/types_gen.go":      true,
+		"api/types_gen.go":          false,
+		"srcs/data.json":            false,
+		"src/api/types_gen.go.orig": false,
+	}
+	for p, want := range matchTests {
+		assert.Equal(t, want, f.MatchesPath(p), "path %q", p)
+	}
+
+	withinTests := map[string]struct {
+		could bool
+		all   bool
+	}{
+		"src":          {could: true, all: false},
+		"src/fixtures": {could: true, all: false},
+		"src/docs":     {could: true, all: false},
+		"other":        {could: false, all: false},
+		"srcs":         {could: false, all: false},
+	}
+	for dir, want := range withinTests {
+		assert.Equal(t, want.could, f.CouldMatchWithin(dir), "could match within %q", dir)
+		assert.Equal(t, want.all, f.MatchesAllWithin(dir), "matches all within %q", dir)
+	}
+
+	f, err = CompileFilterPatternFileAt(path, "src/api")
+	require.NoError(t, err)
+	assert.True(t, f.CouldMatchWithin("src"))
+	assert.False(t, f.CouldMatchWithin("src/web"))
+	assert.True(t, f.MatchesPath("src/api/fixtures/x"))
+	// A file within the directory is re-included.
+	assert.False(t, f.MatchesAllWithin("src/api/fixtures"))
+}
@@ -0,0 +1,63 @@
+package filter
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// CompileGitignorePatterns compiles lines of a gitignore file which lives in the given base
+// directory (relative to the root of the repository, or "" for the root itself). Unlike filter
+// patterns, these follow git's own anchoring rules: a pattern containing a slash (other than a
+// trailing one) is anchored to the base directory, while others match at any depth beneath it.
+// Patterns ending with a slash only match directories (see MatchesDirHow), and matching a
+// directory does not imply matching its contents.
+func CompileGitignorePatterns(source, base string, lines ...string) (*Filter, error) {
+	prefix := "^"
+	if base = strings.Trim(base, "/"); base != "" {
+		prefix += regexp.QuoteMeta(base + "/")
+	}
+	return compilePatterns(lines, source, false, false, func(line string) (*Pattern, error) {
+		return getGitignorePatternFromLine(line, prefix)
+	})
+}
+
+// CompileGitignoreFile reads and compiles a gitignore file which lives in the given base directory
+// (see CompileGitignorePatterns).
+func CompileGitignoreFile(path, base string) (*Filter, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return CompileGitignorePatterns(path, base, strings.Split(string(bs), "\n")...)
+}
+
+func getGitignorePatternFromLine(line, prefix string) (*Pattern, error) {
+	line, negatePattern, ok := cutPatternPrefix(line)
+	if !ok {
+		return nil, nil
+	}
+
+	glob, dirOnly := strings.CutSuffix(line, "/")
+	if glob == "" {
+		return nil, nil
+	}
+
+	// Patterns without a slash may match at any depth.
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+	} else {
+		prefix += "(?:.*/)?"
+	}
+
+	expr, err := wildmatchToRegexp(glob)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := regexp.Compile("(?s)" + prefix + expr + "$")
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{Pattern: pattern, Negate: negatePattern, DirOnly: dirOnly}, nil
+}
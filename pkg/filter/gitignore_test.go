@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileGitignorePatterns(t *testing.T) {
+	tests := map[string]struct {
+		base     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		"name matches at any depth": {
+			patterns: []string{"*.log"},
+			path:     "deep/nested/debug.log",
+			want:     true,
+		},
+		"leading slash anchors to base": {
+			patterns: []string{"/debug.log"},
+			path:     "nested/debug.log",
+			want:     false,
+		},
+		"middle slash anchors to base": {
+			patterns: []string{"doc/*.txt"},
+			path:     "nested/doc/notes.txt",
+			want:     false,
+		},
+		"anchored pattern within base": {
+			base:     "pkg",
+			patterns: []string{"doc/*.txt"},
+			path:     "pkg/doc/notes.txt",
+			want:     true,
+		},
+		"name matches at any depth within base": {
+			base:     "pkg",
+			patterns: []string{"*.log"},
+			path:     "pkg/a/b/debug.log",
+			want:     true,
+		},
+		"patterns do not apply outside base": {
+			base:     "pkg",
+			patterns: []string{"*.log"},
+			path:     "other/debug.log",
+			want:     false,
+		},
+		"base is not a prefix of a sibling": {
+			base:     "pkg",
+			patterns: []string{"*.log"},
+			path:     "pkg2/debug.log",
+			want:     false,
+		},
+		"trailing slash matches directory": {
+			patterns: []string{"build/"},
+			path:     "src/build",
+			isDir:    true,
+			want:     true,
+		},
+		"trailing slash does not match file": {
+			patterns: []string{"build/"},
+			path:     "src/build",
+			want:     false,
+		},
+		"matching a directory does not match its contents": {
+			patterns: []string{"build"},
+			path:     "build/out.bin",
+			want:     false,
+		},
+		"negation re-includes": {
+			patterns: []string{"*.log", "!keep.log"},
+			path:     "keep.log",
+			want:     false,
+		},
+		"escaped leading characters": {
+			patterns: []string{`\#notes`, `\!important`},
+			path:     "!important",
+			want:     true,
+		},
+		"leading spaces are significant": {
+			patterns: []string{"  foo"},
+			path:     "foo",
+			want:     false,
+		},
+		"leading spaces match names starting with spaces": {
+			patterns: []string{"  foo"},
+			path:     "  foo",
+			want:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := CompileGitignorePatterns(".gitignore", tt.base, tt.patterns...)
+			require.NoError(t, err)
+
+			var got bool
+			if tt.isDir {
+				got, _ = f.MatchesDirHow(tt.path)
+			} else {
+				got = f.MatchesPath(tt.path)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompileGitignorePatternsSource(t *testing.T) {
+	f, err := CompileGitignorePatterns("pkg/.gitignore", "pkg", "# comment", "", "*.tmp")
+	require.NoError(t, err)
+
+	matches, pattern := f.MatchesPathHow("pkg/a.tmp")
+	assert.True(t, matches)
+	require.NotNil(t, pattern)
+	assert.Equal(t, "pkg/.gitignore", pattern.Source)
+	assert.Equal(t, 3, pattern.LineNo)
+	assert.Equal(t, "*.tmp", pattern.Line)
+}
@@ -0,0 +1,222 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrTrailingBackslash is returned for patterns ending with an unescaped backslash.
+	ErrTrailingBackslash = errors.New("trailing backslash")
+	// ErrUnclosedBracket is returned for bracket expressions without a closing ']'.
+	ErrUnclosedBracket = errors.New("unclosed bracket expression")
+	// ErrInvalidClass is returned for unknown character classes (eg. "[[:spaci:]]").
+	ErrInvalidClass = errors.New("invalid character class")
+)
+
+// runeRange is an inclusive range of characters within a bracket expression.
+type runeRange struct {
+	lo, hi rune
+}
+
+// characterClasses are the POSIX character classes supported within bracket expressions.
+var characterClasses = map[string]func(r rune) bool{
+	"alnum":  func(r rune) bool { return isAlpha(r) || isDigit(r) },
+	"alpha":  isAlpha,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl":  func(r rune) bool { return r < ' ' || r == 0x7f },
+	"digit":  isDigit,
+	"graph":  func(r rune) bool { return r > ' ' && r < 0x7f },
+	"lower":  func(r rune) bool { return r >= 'a' && r <= 'z' },
+	"print":  func(r rune) bool { return r >= ' ' && r < 0x7f },
+	"punct":  func(r rune) bool { return r > ' ' && r < 0x7f && !isAlpha(r) && !isDigit(r) },
+	"space":  func(r rune) bool { return r == ' ' || (r >= '\t' && r <= '\r') },
+	"upper":  func(r rune) bool { return r >= 'A' && r <= 'Z' },
+	"xdigit": func(r rune) bool { return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') },
+}
+
+func isAlpha(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+func isDigit(r rune) bool { return r >= '0' && r <= '9' }
+
+// wildmatchToRegexp translates a glob into an unanchored regular expression which follows the
+// semantics of git's wildmatch with WM_PATHNAME: '*', '?' and bracket expressions never match '/',
+// while "**" matches across directories when it is a whole path component (eg. "**/", "/**/" and
+// "/**"). Elsewhere "**" is equivalent to '*'. A backslash escapes the following character.
+func wildmatchToRegexp(glob string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(glob); {
+		switch c := glob[i]; c {
+		case '\\':
+			if i+1 >= len(glob) {
+				return "", ErrTrailingBackslash
+			}
+			r, size := utf8.DecodeRuneInString(glob[i+1:])
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			i += 1 + size
+
+		case '?':
+			sb.WriteString(`[^/]`)
+			i++
+
+		case '*':
+			start := i
+			for i < len(glob) && glob[i] == '*' {
+				i++
+			}
+			atBoundary := start == 0 || glob[start-1] == '/'
+			switch {
+			case i-start < 2 || !atBoundary:
+				sb.WriteString(`[^/]*`)
+			case i == len(glob):
+				sb.WriteString(`.*`)
+			case glob[i] == '/':
+				sb.WriteString(`(?:.*/)?`)
+				i++
+			case strings.HasPrefix(glob[i:], `\/`):
+				sb.WriteString(`(?:.*/)?`)
+				i += 2
+			default:
+				sb.WriteString(`[^/]*`)
+			}
+
+		case '[':
+			expr, n, err := bracketToRegexp(glob[i:])
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(expr)
+			i += n
+
+		default:
+			r, size := utf8.DecodeRuneInString(glob[i:])
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			i += size
+		}
+	}
+	return sb.String(), nil
+}
+
+// bracketToRegexp translates the bracket expression at the start of s (eg. "[a-z]", "[!abc]",
+// "[^[:digit:]]" or "[]-]"), returning the regular expression and the number of bytes consumed.
+// As with wildmatch, a ']' immediately after the opening bracket (or its negation) is literal,
+// a '-' is literal at the start or end of the expression or directly after a range, and a
+// '[' which does not begin a character class is literal.
+func bracketToRegexp(s string) (string, int, error) {
+	i := 1
+	negated := false
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		negated = true
+		i++
+	}
+
+	ranges := make([]runeRange, 0)
+	var prev rune = -1 // The previous literal character, which may begin a range
+	for first := true; ; first = false {
+		if i >= len(s) {
+			return "", 0, ErrUnclosedBracket
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == ']' && !first {
+			i++
+			break
+		}
+
+		switch {
+		case c == '\\':
+			i++
+			if i >= len(s) {
+				return "", 0, ErrUnclosedBracket
+			}
+			c, size = utf8.DecodeRuneInString(s[i:])
+			ranges = append(ranges, runeRange{c, c})
+			prev = c
+			i += size
+
+		case c == '-' && prev >= 0 && i+1 < len(s) && s[i+1] != ']':
+			i++
+			hi, size := utf8.DecodeRuneInString(s[i:])
+			if hi == '\\' {
+				i++
+				if i >= len(s) {
+					return "", 0, ErrUnclosedBracket
+				}
+				hi, size = utf8.DecodeRuneInString(s[i:])
+			}
+			// Ranges where the bounds are reversed match nothing.
+			if prev <= hi {
+				ranges = append(ranges, runeRange{prev, hi})
+			}
+			prev = -1
+			i += size
+
+		case c == '[' && strings.HasPrefix(s[i:], "[:"):
+			end := strings.IndexByte(s[i+2:], ']')
+			if end < 0 {
+				return "", 0, ErrUnclosedBracket
+			}
+			name := s[i+2 : i+2+end]
+			if !strings.HasSuffix(name, ":") {
+				// Without a closing ":]", the '[' is literal.
+				ranges = append(ranges, runeRange{c, c})
+				prev = c
+				i++
+				continue
+			}
+			name = strings.TrimSuffix(name, ":")
+			isClass, ok := characterClasses[name]
+			if !ok {
+				return "", 0, fmt.Errorf("%w %q", ErrInvalidClass, "[:"+name+":]")
+			}
+			for r := rune(0); r < utf8.RuneSelf; r++ {
+				if isClass(r) {
+					ranges = append(ranges, runeRange{r, r})
+				}
+			}
+			prev = -1
+			i += 2 + end + 1
+
+		default:
+			ranges = append(ranges, runeRange{c, c})
+			prev = c
+			i += size
+		}
+	}
+
+	// Bracket expressions never match '/'.
+	var sb strings.Builder
+	if negated {
+		sb.WriteString(`[^/`)
+		for _, r := range ranges {
+			writeRange(&sb, r)
+		}
+		sb.WriteString(`]`)
+		return sb.String(), i, nil
+	}
+
+	count := 0
+	sb.WriteString(`[`)
+	for _, r := range ranges {
+		for _, part := range []runeRange{{r.lo, min(r.hi, '/'-1)}, {max(r.lo, '/'+1), r.hi}} {
+			if part.lo <= part.hi {
+				writeRange(&sb, part)
+				count++
+			}
+		}
+	}
+	sb.WriteString(`]`)
+	if count == 0 {
+		// Only '/' was included, so nothing may match.
+		return `[^\x00-\x{10FFFF}]`, i, nil
+	}
+	return sb.String(), i, nil
+}
+
+func writeRange(sb *strings.Builder, r runeRange) {
+	sb.WriteString(fmt.Sprintf(`\x{%x}`, r.lo))
+	if r.hi != r.lo {
+		sb.WriteString(fmt.Sprintf(`-\x{%x}`, r.hi))
+	}
+}
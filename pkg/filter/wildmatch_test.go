@@ -0,0 +1,215 @@
+package filter
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wildmatchCases are ported from git's t/t3070-wildmatch.sh, using the results expected when
+// matching with WM_PATHNAME (the "glob" column). Patterns which git aborts on are expected to
+// fail to compile, and so never match.
+var wildmatchCases = []struct {
+	match   bool
+	text    string
+	pattern string
+}{
+	{true, `foo`, `foo`},
+	{false, `foo`, `bar`},
+	{true, ``, ``},
+	{true, `foo`, `???`},
+	{false, `foo`, `??`},
+	{true, `foo`, `*`},
+	{true, `foo`, `f*`},
+	{false, `foo`, `*f`},
+	{true, `foo`, `*foo*`},
+	{true, `foobar`, `*ob*a*r*`},
+	{true, `aaaaaaabababab`, `*ab`},
+	{true, `foo*`, `foo\*`},
+	{false, `foobar`, `foo\*bar`},
+	{true, `f\oo`, `f\\oo`},
+	{true, `ball`, `*[al]?`},
+	{false, `ten`, `[ten]`},
+	{true, `ten`, `**[!te]`},
+	{false, `ten`, `**[!ten]`},
+	{true, `ten`, `t[a-g]n`},
+	{false, `ten`, `t[!a-g]n`},
+	{true, `ton`, `t[!a-g]n`},
+	{true, `ton`, `t[^a-g]n`},
+	{true, `a]b`, `a[]]b`},
+	{true, `a-b`, `a[]-]b`},
+	{true, `a]b`, `a[]-]b`},
+	{false, `aab`, `a[]-]b`},
+	{true, `aab`, `a[]a-]b`},
+	{true, `]`, `]`},
+	{false, `foo/baz/bar`, `foo*bar`},
+	{false, `foo/baz/bar`, `foo**bar`},
+	{true, `foobazbar`, `foo**bar`},
+	{true, `foo/baz/bar`, `foo/**/bar`},
+	{true, `foo/baz/bar`, `foo/**/**/bar`},
+	{true, `foo/b/a/z/bar`, `foo/**/bar`},
+	{true, `foo/b/a/z/bar`, `foo/**/**/bar`},
+	{true, `foo/bar`, `foo/**/bar`},
+	{true, `foo/bar`, `foo/**/**/bar`},
+	{false, `foo/bar`, `foo?bar`},
+	{false, `foo/bar`, `foo[/]bar`},
+	{false, `foo/bar`, `foo[^a-z]bar`},
+	{false, `foo/bar`, `f[^eiu][^eiu][^eiu][^eiu][^eiu]r`},
+	{true, `foo-bar`, `f[^eiu][^eiu][^eiu][^eiu][^eiu]r`},
+	{true, `foo`, `**/foo`},
+	{true, `XXX/foo`, `**/foo`},
+	{true, `bar/baz/foo`, `**/foo`},
+	{false, `bar/baz/foo`, `*/foo`},
+	{false, `foo/bar/baz`, `**/bar*`},
+	{true, `deep/foo/bar/baz`, `**/bar/*`},
+	{false, `deep/foo/bar/baz/`, `**/bar/*`},
+	{true, `deep/foo/bar/baz/`, `**/bar/**`},
+	{false, `deep/foo/bar`, `**/bar/*`},
+	{true, `deep/foo/bar/`, `**/bar/**`},
+	{false, `foo/bar/baz`, `**/bar**`},
+	{true, `foo/bar/baz/x`, `*/bar/**`},
+	{false, `deep/foo/bar/baz/x`, `*/bar/**`},
+	{true, `deep/foo/bar/baz/x`, `**/bar/*/*`},
+	{false, `acrt`, `a[c-c]st`},
+	{true, `acrt`, `a[c-c]rt`},
+	{false, `]`, `[!]-]`},
+	{true, `a`, `[!]-]`},
+	{false, ``, `\`},
+	{false, `\`, `\`},
+	{false, `XXX/\`, `*/\`},
+	{true, `XXX/\`, `*/\\`},
+	{true, `foo`, `foo`},
+	{true, `@foo`, `@foo`},
+	{false, `foo`, `@foo`},
+	{true, `[ab]`, `\[ab]`},
+	{true, `[ab]`, `[[]ab]`},
+	{true, `[ab]`, `[[:]ab]`},
+	{false, `[ab]`, `[[::]ab]`},
+	{true, `[ab]`, `[[:digit]ab]`},
+	{true, `[ab]`, `[\[:]ab]`},
+	{true, `?a?b`, `\??\?b`},
+	{true, `abc`, `\a\b\c`},
+	{false, `foo`, ``},
+	{true, `foo/bar/baz/to`, `**/t[o]`},
+	{true, `a1B`, `[[:alpha:]][[:digit:]][[:upper:]]`},
+	{false, `a`, `[[:digit:][:upper:][:space:]]`},
+	{true, `A`, `[[:digit:][:upper:][:space:]]`},
+	{true, `1`, `[[:digit:][:upper:][:space:]]`},
+	{false, `1`, `[[:digit:][:upper:][:spaci:]]`},
+	{true, ` `, `[[:digit:][:upper:][:space:]]`},
+	{false, `.`, `[[:digit:][:upper:][:space:]]`},
+	{true, `.`, `[[:digit:][:punct:][:space:]]`},
+	{true, `5`, `[[:xdigit:]]`},
+	{true, `f`, `[[:xdigit:]]`},
+	{true, `D`, `[[:xdigit:]]`},
+	{true, `_`, `[[:alnum:][:alpha:][:blank:][:cntrl:][:digit:][:graph:][:lower:][:print:][:punct:][:space:][:upper:][:xdigit:]]`},
+	{true, `.`, `[^[:alnum:][:alpha:][:blank:][:cntrl:][:digit:][:lower:][:space:][:upper:][:xdigit:]]`},
+	{true, `5`, `[a-c[:digit:]x-z]`},
+	{true, `b`, `[a-c[:digit:]x-z]`},
+	{true, `y`, `[a-c[:digit:]x-z]`},
+	{false, `q`, `[a-c[:digit:]x-z]`},
+	{true, `]`, `[\\-^]`},
+	{false, `[`, `[\\-^]`},
+	{true, `-`, `[\-_]`},
+	{true, `]`, `[\]]`},
+	{false, `\]`, `[\]]`},
+	{false, `\`, `[\]]`},
+	{false, `ab`, `a[]b`},
+	{false, `a[]b`, `a[]b`},
+	{false, `ab[`, `ab[`},
+	{false, `ab`, `[!`},
+	{false, `ab`, `[-`},
+	{true, `-`, `[-]`},
+	{false, `-`, `[a-`},
+	{false, `-`, `[!a-`},
+	{true, `-`, `[--A]`},
+	{true, `5`, `[--A]`},
+	{true, ` `, `[ --]`},
+	{true, `$`, `[ --]`},
+	{true, `-`, `[ --]`},
+	{false, `0`, `[ --]`},
+	{true, `-`, `[---]`},
+	{true, `-`, `[------]`},
+	{false, `j`, `[a-e-n]`},
+	{true, `-`, `[a-e-n]`},
+	{true, `a`, `[!------]`},
+	{false, `[`, `[]-a]`},
+	{true, `^`, `[]-a]`},
+	{false, `^`, `[!]-a]`},
+	{true, `[`, `[!]-a]`},
+	{true, `^`, `[a^bc]`},
+	{true, `-b]`, `[a-]b]`},
+	{false, `\`, `[\]`},
+	{true, `\`, `[\\]`},
+	{false, `\`, `[!\\]`},
+	{true, `G`, `[A-\\]`},
+	{false, `aaabbb`, `b*a`},
+	{false, `aabcaa`, `*ba*`},
+	{true, `,`, `[,]`},
+	{true, `,`, `[\\,]`},
+	{true, `\`, `[\\,]`},
+	{true, `-`, `[,-.]`},
+	{false, `+`, `[,-.]`},
+	{false, `-.]`, `[,-.]`},
+	{true, `2`, `[\1-\3]`},
+	{true, `3`, `[\1-\3]`},
+	{false, `4`, `[\1-\3]`},
+	{true, `\`, `[[-\]]`},
+	{true, `[`, `[[-\]]`},
+	{true, `]`, `[[-\]]`},
+	{false, `-`, `[[-\]]`},
+	{true, `-adobe-courier-bold-o-normal--12-120-75-75-m-70-iso8859-1`, `-*-*-*-*-*-*-12-*-*-*-m-*-*-*`},
+	{false, `-adobe-courier-bold-o-normal--12-120-75-75-X-70-iso8859-1`, `-*-*-*-*-*-*-12-*-*-*-m-*-*-*`},
+	{false, `-adobe-courier-bold-o-normal--12-120-75-75-/-70-iso8859-1`, `-*-*-*-*-*-*-12-*-*-*-m-*-*-*`},
+	{true, `XXX/adobe/courier/bold/o/normal//12/120/75/75/m/70/iso8859/1`, `XXX/*/*/*/*/*/*/12/*/*/*/m/*/*/*`},
+	{false, `XXX/adobe/courier/bold/o/normal//12/120/75/75/X/70/iso8859/1`, `XXX/*/*/*/*/*/*/12/*/*/*/m/*/*/*`},
+	{true, `abcd/abcdefg/abcdefghijk/abcdefghijklmnop.txt`, `**/*a*b*g*n*t`},
+	{false, `abcd/abcdefg/abcdefghijk/abcdefghijklmnop.txtz`, `**/*a*b*g*n*t`},
+	{false, `foo`, `*/*/*`},
+	{false, `foo/bar`, `*/*/*`},
+	{true, `foo/bba/arr`, `*/*/*`},
+	{false, `foo/bb/aa/rr`, `*/*/*`},
+	{true, `foo/bb/aa/rr`, `**/**/**`},
+	{true, `abcXdefXghi`, `*X*i`},
+	{false, `ab/cXd/efXg/hi`, `*X*i`},
+	{true, `ab/cXd/efXg/hi`, `*/*X*/*/*i`},
+	{true, `ab/cXd/efXg/hi`, `**/*X*/**/*i`},
+	{false, `foo`, `fo`},
+	{true, `foo/bar`, `foo/bar`},
+	{true, `foo/bar`, `foo/*`},
+	{false, `foo/bba/arr`, `foo/*`},
+	{true, `foo/bba/arr`, `foo/**`},
+	{false, `foo/bba/arr`, `foo*`},
+	{false, `foo/bba/arr`, `foo**`},
+	{false, `foo/bba/arr`, `foo/*arr`},
+	{false, `foo/bba/arr`, `foo/**arr`},
+	{false, `foo/bba/arr`, `foo/*z`},
+	{false, `foo/bba/arr`, `foo/**z`},
+	{false, `foo/bar`, `foo?bar`},
+	{false, `foo/bar`, `foo[/]bar`},
+	{false, `foo/bar`, `foo[^a-z]bar`},
+	{false, `ab/cXd/efXg/hi`, `*Xg*i`},
+	{false, `a`, `[A-Z]`},
+	{true, `A`, `[A-Z]`},
+	{false, `A`, `[a-z]`},
+	{true, `a`, `[a-z]`},
+	{false, `a`, `[[:upper:]]`},
+	{true, `A`, `[[:upper:]]`},
+	{false, `A`, `[[:lower:]]`},
+	{true, `a`, `[[:lower:]]`},
+	{false, `A`, `[B-Za]`},
+	{true, `a`, `[B-Za]`},
+	{false, `A`, `[B-a]`},
+	{true, `a`, `[B-a]`},
+	{false, `z`, `[Z-y]`},
+	{true, `Z`, `[Z-y]`},
+}
+
+func TestWildmatchConformance(t *testing.T) {
+	for _, tc := range wildmatchCases {
+		expr, err := wildmatchToRegexp(tc.pattern)
+		got := err == nil && regexp.MustCompile(`(?s)^`+expr+`$`).MatchString(tc.text)
+		assert.Equal(t, tc.match, got, "text: %q, pattern: %q, regexp: %q, error: %v", tc.text, tc.pattern, expr, err)
+	}
+}